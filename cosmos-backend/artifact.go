@@ -2,7 +2,7 @@ package cosmos
 
 import (
 	"context"
-	"log"
+	"fmt"
 	"sync"
 	"time"
 )
@@ -25,6 +25,9 @@ const (
 	ArtifactDstCatalog
 	ArtifactBeforeState
 	ArtifactAfterState
+	ArtifactQuarantine
+	ArtifactFieldPolicies
+	ArtifactSample
 	ArtifactMax
 )
 
@@ -39,21 +42,106 @@ var ArtifactNames = [ArtifactMax]string{
 	"destination-catalog",
 	"before-state",
 	"after-state",
+	"quarantine",
+	"field-policies",
+	"sample",
+}
+
+// IsLogArtifact returns true for the artifacts that are written line by line using GetArtifactRef.
+// Log artifacts are stored separately for every attempt of a run.
+func IsLogArtifact(id int) bool {
+	switch id {
+	case ArtifactSource, ArtifactDestination, ArtifactNormalization, ArtifactWorker, ArtifactQuarantine:
+		return true
+	}
+	return false
+}
+
+// ArtifactFileName returns the name of the file in which an attempt of a log artifact is stored.
+// Runs from before log artifacts were stored per attempt have a single file with all the attempts,
+// which is listed as attempt 0.
+func ArtifactFileName(id int, attempt int32) string {
+	if attempt == 0 {
+		return ArtifactNames[id]
+	}
+	return fmt.Sprintf("%s.%d", ArtifactNames[id], attempt)
+}
+
+// ArtifactFile describes a stored artifact. Log artifacts have one file per attempt.
+type ArtifactFile struct {
+	ID      int        `json:"id"`
+	Name    string     `json:"name"`
+	Attempt int32      `json:"attempt,omitempty"`
+	Size    int64      `json:"size"`
+	Start   *time.Time `json:"start,omitempty"`
+	End     *time.Time `json:"end,omitempty"`
+}
+
+type artifactKey struct {
+	id      int
+	attempt int32
 }
 
 type Artifactory struct {
-	Path      string
-	Once      [ArtifactMax]sync.Once
-	Artifacts [ArtifactMax]*log.Logger
+	Path string
+
+	mu        sync.Mutex
+	artifacts map[artifactKey]*ArtifactLogger
+}
+
+// Logger returns the logger for an attempt of a log artifact. The logger is created using
+// create the first time it is requested and the same logger is returned thereafter.
+func (a *Artifactory) Logger(id int, attempt int32, create func() (*ArtifactLogger, error)) (*ArtifactLogger, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := artifactKey{id, attempt}
+	if logger, ok := a.artifacts[key]; ok {
+		return logger, nil
+	}
+
+	logger, err := create()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.artifacts == nil {
+		a.artifacts = map[artifactKey]*ArtifactLogger{}
+	}
+	a.artifacts[key] = logger
+
+	return logger, nil
+}
+
+// Loggers returns all the loggers that have been created in the artifactory.
+func (a *Artifactory) Loggers() []*ArtifactLogger {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	loggers := []*ArtifactLogger{}
+	for _, logger := range a.artifacts {
+		loggers = append(loggers, logger)
+	}
+	return loggers
 }
 
 type ArtifactService interface {
 	GetArtifactory(syncID int, executionDate time.Time) (*Artifactory, error)
-	GetArtifactRef(artifactory *Artifactory, id int, attempt int32) (*log.Logger, error)
+
+	// LookupArtifactory returns the artifactory of a run without creating it, unlike GetArtifactory.
+	// The artifactory might not exist.
+	LookupArtifactory(syncID int, executionDate time.Time) *Artifactory
+
+	GetArtifactRef(artifactory *Artifactory, id int, attempt int32) (*ArtifactLogger, error)
 	WriteArtifact(artifactory *Artifactory, id int, contents interface{}) error
 	GetArtifactPath(artifactory *Artifactory, id int) *string
 	GetArtifactData(artifactory *Artifactory, id int) ([]byte, error)
+	GetArtifactAttemptData(artifactory *Artifactory, id int, attempt int32) ([]byte, error)
+	GetArtifactDataFrom(artifactory *Artifactory, id int, offset int64) ([]byte, int64, error)
 	CloseArtifactory(artifactory *Artifactory)
+	ListArtifacts(artifactory *Artifactory) ([]*ArtifactFile, error)
+	GetArtifactorySize(artifactory *Artifactory) (int64, error)
+	DeleteArtifactory(artifactory *Artifactory) error
 }
 
 func NewArtifactoryContext(ctx context.Context, artifactory *Artifactory) context.Context {
@@ -63,3 +151,12 @@ func NewArtifactoryContext(ctx context.Context, artifactory *Artifactory) contex
 func ArtifactoryFromContext(ctx context.Context) *Artifactory {
 	return ctx.Value(artifactoryKey).(*Artifactory)
 }
+
+// GetRunArtifactory returns the artifactory of a run. An EPURGED error is
+// returned if the artifacts of the run have been removed by the retention policy.
+func (a *App) GetRunArtifactory(run *Run) (*Artifactory, error) {
+	if run.ArtifactsPurged {
+		return nil, Errorf(EPURGED, "The artifacts of run %d have been purged by the retention policy", run.ID)
+	}
+	return a.GetArtifactory(run.SyncID, run.ExecutionDate)
+}
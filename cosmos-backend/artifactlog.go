@@ -0,0 +1,345 @@
+package cosmos
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Output formats of log artifacts. Log artifacts are stored as JSON lines.
+const (
+	LogFormatJSON = "json"
+	LogFormatText = "text"
+)
+
+// Lifecycle events of a run.
+const (
+	EventReplicationStart   = "replication_start"
+	EventRetry              = "retry"
+	EventContainerStart     = "container_start"
+	EventContainerExit      = "container_exit"
+	EventFirstRecord        = "first_record"
+	EventState              = "state"
+	EventLastState          = "last_state"
+	EventNormalizationStart = "normalization_start"
+	EventRunStart           = "run_start"
+	EventRunEnd             = "run_end"
+)
+
+// Event is a lifecycle event of a run. Events are written to the log artifacts
+// along with the logs so that they can be put on the timeline of the run.
+type Event struct {
+	Type    string
+	Message string
+}
+
+// LogLine is a single line of a log artifact.
+type LogLine struct {
+	Timestamp time.Time `json:"timestamp"`
+	Level     string    `json:"level,omitempty"`
+	Stage     string    `json:"stage"`
+	Attempt   int32     `json:"attempt"`
+	Event     string    `json:"event,omitempty"`
+	Message   string    `json:"message"`
+}
+
+// String renders the line as colorized text.
+func (l *LogLine) String() string {
+	message := l.Message
+	if _, ok := logLevelSeverity[l.Level]; ok {
+		message = (&Log{Level: l.Level, Message: l.Message}).String()
+	}
+	if l.Event != "" {
+		message = fmt.Sprintf("[%s] %s", l.Event, message)
+	}
+	return fmt.Sprintf("[Attempt %3d] %s %s", l.Attempt, l.Timestamp.Local().Format(logTextTimeLayout), message)
+}
+
+// logTextTimeLayout is the layout of the timestamps in the text rendering of log lines.
+const logTextTimeLayout = "2006/01/02 15:04:05"
+
+// ArtifactLogger writes the lines of an attempt of a log artifact as JSON lines.
+type ArtifactLogger struct {
+	mu      sync.Mutex
+	w       io.WriteCloser
+	stage   string
+	attempt int32
+}
+
+func NewArtifactLogger(w io.WriteCloser, id int, attempt int32) *ArtifactLogger {
+	return &ArtifactLogger{
+		w:       w,
+		stage:   ArtifactNames[id],
+		attempt: attempt,
+	}
+}
+
+// Println writes a line to the log artifact. The level of a *Log and the type of an *Event are preserved.
+// Everything else is written as the message of the line.
+func (l *ArtifactLogger) Println(v interface{}) {
+	line := &LogLine{
+		Timestamp: time.Now().UTC(),
+		Stage:     l.stage,
+		Attempt:   l.attempt,
+	}
+
+	switch v := v.(type) {
+	case *Log:
+		line.Level, line.Message = v.Level, v.Message
+	case *Event:
+		line.Event, line.Message = v.Type, v.Message
+	case string:
+		line.Message = v
+	default:
+		line.Message = fmt.Sprint(v)
+	}
+
+	b, err := json.Marshal(line)
+	if err != nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Write(append(b, '\n'))
+}
+
+func (l *ArtifactLogger) Close() error {
+	return l.w.Close()
+}
+
+// ParseLogLine parses a line of a log artifact. Log artifacts of older runs were written as
+// text, so lines which are not JSON are returned as the message with a best-effort level.
+func ParseLogLine(b []byte) (*LogLine, bool) {
+	line := &LogLine{}
+	if err := json.Unmarshal(b, line); err == nil {
+		return line, true
+	}
+	return &LogLine{Level: textLogLineLevel(b), Message: string(bytes.TrimRight(b, "\r\n"))}, false
+}
+
+// FormatLogLines renders the JSON lines of a log artifact in the given format.
+func FormatLogLines(data []byte, format string) []byte {
+	if format != LogFormatText {
+		return data
+	}
+
+	var out []byte
+	for _, b := range bytes.SplitAfter(data, []byte("\n")) {
+		if len(b) == 0 {
+			continue
+		}
+		if line, ok := ParseLogLine(b); ok {
+			out = append(out, line.String()+"\n"...)
+		} else {
+			out = append(out, b...)
+		}
+	}
+	return out
+}
+
+// FilterLogLines returns the lines of a log artifact whose level is at least as severe as the given level.
+// Lines which don't have a level, like the raw output of connectors, are dropped.
+func FilterLogLines(data []byte, level string) []byte {
+	min, ok := logLevelSeverity[level]
+	if !ok {
+		return data
+	}
+
+	var out []byte
+	for _, b := range bytes.SplitAfter(data, []byte("\n")) {
+		if len(b) == 0 {
+			continue
+		}
+		line, _ := ParseLogLine(b)
+		if severity, ok := logLevelSeverity[line.Level]; ok && severity >= min {
+			out = append(out, b...)
+		}
+	}
+	return out
+}
+
+// IsValidLogLevel returns true if level is one of the log levels.
+func IsValidLogLevel(level string) bool {
+	_, ok := logLevelSeverity[level]
+	return ok
+}
+
+var logLevelSeverity = map[string]int{
+	LogLevelTrace: 0,
+	LogLevelDebug: 1,
+	LogLevelInfo:  2,
+	LogLevelWarn:  3,
+	LogLevelError: 4,
+	LogLevelFatal: 5,
+}
+
+// textLogLineLevel returns the level of a line of a text log artifact or an empty string if it doesn't have one.
+func textLogLineLevel(line []byte) string {
+	for level := range logLevelSeverity {
+		if bytes.Contains(line, []byte("m"+level+"\u001b[0m ")) {
+			return level
+		}
+	}
+	return ""
+}
+
+// ParseArtifactLogTime returns the time at which the first line of a log artifact was written.
+// Only the beginning of the line is required.
+func ParseArtifactLogTime(b []byte) (time.Time, bool) {
+	const prefix = `{"timestamp":"`
+	if bytes.HasPrefix(b, []byte(prefix)) {
+		b = b[len(prefix):]
+		i := bytes.IndexByte(b, '"')
+		if i < 0 {
+			return time.Time{}, false
+		}
+		t, err := time.Parse(time.RFC3339Nano, string(b[:i]))
+		return t, err == nil
+	}
+
+	// Lines of text log artifacts start with "[Attempt   N] 2006/01/02 15:04:05".
+	i := bytes.IndexByte(b, ']')
+	if i < 0 || len(b) < i+2+len(logTextTimeLayout) {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(logTextTimeLayout, string(b[i+2:i+2+len(logTextTimeLayout)]), time.Local)
+	return t, err == nil
+}
+
+// LogFilter represents a filter passed to SearchLogs.
+type LogFilter struct {
+	Level   string `json:"level"`
+	Stage   string `json:"stage"`
+	Query   string `json:"query"`
+	Attempt *int32 `json:"attempt"`
+
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// logStages are the log artifacts which are searched by SearchLogs.
+var logStages = []int{
+	ArtifactSource,
+	ArtifactDestination,
+	ArtifactNormalization,
+	ArtifactWorker,
+}
+
+// SearchLogs returns the log lines of all the stages of a run which match the filter ordered by
+// time along with the total number of matching lines.
+func (a *App) SearchLogs(ctx context.Context, run *Run, filter LogFilter) ([]*LogLine, int, error) {
+	if filter.Level != "" && !IsValidLogLevel(filter.Level) {
+		return nil, 0, Errorf(EINVALID, "Invalid log level: %s", filter.Level)
+	}
+
+	stages := map[string]bool{}
+	for _, id := range logStages {
+		stages[ArtifactNames[id]] = true
+	}
+	if filter.Stage != "" && !stages[filter.Stage] {
+		return nil, 0, Errorf(EINVALID, "Invalid log stage: %s", filter.Stage)
+	}
+
+	artifactory, err := a.GetRunArtifactory(run)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	files, err := a.ListArtifacts(artifactory)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := strings.ToLower(filter.Query)
+	lines := []*LogLine{}
+
+	for _, f := range files {
+		if !stages[f.Name] || (filter.Stage != "" && f.Name != filter.Stage) {
+			continue
+		}
+		if filter.Attempt != nil && f.Attempt != *filter.Attempt {
+			continue
+		}
+
+		data, err := a.GetArtifactAttemptData(artifactory, f.ID, f.Attempt)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		for _, b := range bytes.Split(data, []byte("\n")) {
+			if len(b) == 0 {
+				continue
+			}
+			line, ok := ParseLogLine(b)
+			if !ok {
+				line.Stage, line.Attempt = f.Name, f.Attempt
+			}
+			if filter.Level != "" {
+				if severity, ok := logLevelSeverity[line.Level]; !ok || severity < logLevelSeverity[filter.Level] {
+					continue
+				}
+			}
+			if query != "" && !strings.Contains(strings.ToLower(line.Message), query) {
+				continue
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].Timestamp.Before(lines[j].Timestamp)
+	})
+
+	total := len(lines)
+	if filter.Offset > 0 {
+		if filter.Offset > len(lines) {
+			filter.Offset = len(lines)
+		}
+		lines = lines[filter.Offset:]
+	}
+	if filter.Limit > 0 && filter.Limit < len(lines) {
+		lines = lines[:filter.Limit]
+	}
+
+	return lines, total, nil
+}
+
+// RunTimeline returns the logs, state checkpoints and lifecycle events of all the stages of a run
+// as a single stream of lines ordered by time.
+func (a *App) RunTimeline(ctx context.Context, run *Run) ([]*LogLine, error) {
+	lines, _, err := a.SearchLogs(ctx, run, LogFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	timeline := []*LogLine{}
+	if !run.Stats.ExecutionStart.IsZero() {
+		timeline = append(timeline, &LogLine{
+			Timestamp: run.Stats.ExecutionStart,
+			Stage:     "run",
+			Event:     EventRunStart,
+			Message:   fmt.Sprintf("Run %d of sync %d started", run.ID, run.SyncID),
+		})
+	}
+	timeline = append(timeline, lines...)
+	if run.IsTerminalState() && !run.Stats.ExecutionEnd.IsZero() {
+		timeline = append(timeline, &LogLine{
+			Timestamp: run.Stats.ExecutionEnd,
+			Stage:     "run",
+			Event:     EventRunEnd,
+			Message:   fmt.Sprintf("Run finished with status %s", run.Status),
+		})
+	}
+
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Timestamp.Before(timeline[j].Timestamp)
+	})
+
+	return timeline, nil
+}
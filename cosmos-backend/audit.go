@@ -0,0 +1,211 @@
+package cosmos
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Audited actions.
+const (
+	AuditActionCreate     = "create"
+	AuditActionUpdate     = "update"
+	AuditActionDelete     = "delete"
+	AuditActionRediscover = "rediscover"
+	AuditActionSyncNow    = "sync_now"
+	AuditActionCancel     = "cancel"
+)
+
+// Types of audited entities.
+const (
+	AuditEntityConnector       = "connector"
+	AuditEntityEndpoint        = "endpoint"
+	AuditEntitySync            = "sync"
+	AuditEntityRun             = "run"
+	AuditEntityUser            = "user"
+	AuditEntityToken           = "token"
+	AuditEntityPermission      = "permission"
+	AuditEntityWorkspace       = "workspace"
+	AuditEntityWorkspaceMember = "workspace_member"
+)
+
+// AuditEntry records an action that a user performed on an entity, along with the changes that it made.
+// The username is kept so that the entry remains meaningful after the user has been deleted.
+type AuditEntry struct {
+	ID         int            `json:"id"`
+	UserID     int            `json:"userID"`
+	Username   string         `json:"username"`
+	Action     string         `json:"action"`
+	EntityType string         `json:"entityType"`
+	EntityID   int            `json:"entityID"`
+	Changes    []*AuditChange `json:"changes"`
+	CreatedAt  time.Time      `json:"createdAt"`
+}
+
+// AuditChange is a change to a single field of an entity. The path is the dot separated
+// path of the field in the JSON representation of the entity, e.g, "config.catalog.0.isStreamSelected".
+type AuditChange struct {
+	Path   string      `json:"path"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditFilter represents an audit log search filter.
+type AuditFilter struct {
+	UserID     *int    `json:"userID"`
+	Action     *string `json:"action"`
+	EntityType *string `json:"entityType"`
+	EntityID   *int    `json:"entityID"`
+
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+type AuditService interface {
+	FindAuditEntries(ctx context.Context, filter AuditFilter) ([]*AuditEntry, int, error)
+	CreateAuditEntry(ctx context.Context, entry *AuditEntry) error
+}
+
+const auditKey ctxKey = "audit"
+
+// Audit is an action that the authenticated user performs on an entity by creating, updating or
+// deleting it. It is passed to the DBService in the context of the mutation, which records it in
+// the same transaction, so that the entry is only written if the mutation is and vice versa.
+type Audit struct {
+	user       *User
+	action     string
+	entityType string
+	before     interface{}
+	after      interface{}
+}
+
+// Entry returns the audit log entry of the action on the entity with the given ID. The changes are
+// computed when it is called, i.e, once the mutation has set the ID and the other fields of the entity.
+func (a *Audit) Entry(entityID int) *AuditEntry {
+	changes := []*AuditChange{}
+	diffSnapshots("", auditSnapshot(a.before), auditSnapshot(a.after), &changes)
+
+	return &AuditEntry{
+		UserID:     a.user.ID,
+		Username:   a.user.Username,
+		Action:     a.action,
+		EntityType: a.entityType,
+		EntityID:   entityID,
+		Changes:    changes,
+	}
+}
+
+// NewAuditContext returns a context for a mutation which is recorded in the audit log. before and after
+// are the entity before and after the mutation (nil if it didn't exist). Mutations performed by cosmos
+// itself, e.g, saving the state of a sync at the end of a run, are not recorded.
+func NewAuditContext(ctx context.Context, action, entityType string, before, after interface{}) context.Context {
+	user := UserFromContext(ctx)
+	if user == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, auditKey, &Audit{user: user, action: action, entityType: entityType, before: before, after: after})
+}
+
+// AuditFromContext returns the action that must be recorded along with the mutation performed with ctx.
+// ok is false if the mutation is not recorded.
+func AuditFromContext(ctx context.Context) (audit *Audit, ok bool) {
+	audit, ok = ctx.Value(auditKey).(*Audit)
+	return audit, ok
+}
+
+// audit records an action performed by the authenticated user which doesn't mutate an entity in
+// the database, e.g, cancelling a run.
+func (a *App) audit(ctx context.Context, action, entityType string, entityID int, before, after interface{}) error {
+	audit, ok := AuditFromContext(NewAuditContext(ctx, action, entityType, before, after))
+	if !ok {
+		return nil
+	}
+	return a.CreateAuditEntry(ctx, audit.Entry(entityID))
+}
+
+// auditSnapshot returns the JSON representation of an entity for the audit log. Secrets and the salts
+// of field policies are redacted.
+// Fields which are derived from other fields or are refreshed by cosmos (e.g, discovered catalogs) are left out.
+func auditSnapshot(v interface{}) interface{} {
+	var omit []string
+	switch e := v.(type) {
+	case nil:
+		return nil
+	case *Connector:
+		omit = []string{"spec", "createdAt", "updatedAt"}
+	case *Endpoint:
+		endpoint := *e
+		endpoint.Config = *e.Config.Redacted()
+		v = &endpoint
+		omit = []string{"catalog", "connector", "lastDiscovered", "createdAt", "updatedAt"}
+	case *Sync:
+		sync := *e
+		sync.Config = *e.Config.MaskedFieldPolicies()
+		v = &sync
+		omit = []string{"sourceEndpoint", "destinationEndpoint", "configuredCatalog", "createdAt", "updatedAt"}
+	case *User:
+		omit = []string{"createdAt", "updatedAt"}
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var snapshot interface{}
+	if err := json.Unmarshal(b, &snapshot); err != nil {
+		return nil
+	}
+
+	if m, ok := snapshot.(map[string]interface{}); ok {
+		for _, key := range omit {
+			delete(m, key)
+		}
+	}
+
+	return snapshot
+}
+
+// diffSnapshots appends the changes between two JSON values to changes. Objects and arrays
+// of the same length are compared element by element. Missing objects are treated as empty.
+func diffSnapshots(path string, before, after interface{}, changes *[]*AuditChange) {
+	join := func(key string) string {
+		if path == "" {
+			return key
+		}
+		return path + "." + key
+	}
+
+	bm, bok := before.(map[string]interface{})
+	am, aok := after.(map[string]interface{})
+	if (bok || before == nil) && (aok || after == nil) && (bok || aok) {
+		keys := []string{}
+		for k := range bm {
+			keys = append(keys, k)
+		}
+		for k := range am {
+			if _, ok := bm[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			diffSnapshots(join(k), bm[k], am[k], changes)
+		}
+		return
+	}
+
+	bs, bok := before.([]interface{})
+	as, aok := after.([]interface{})
+	if bok && aok && len(bs) == len(as) {
+		for i := range bs {
+			diffSnapshots(join(strconv.Itoa(i)), bs[i], as[i], changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		*changes = append(*changes, &AuditChange{Path: path, Before: before, After: after})
+	}
+}
@@ -0,0 +1,487 @@
+package cosmos
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	authKey ctxKey = "auth"
+
+	// tokenPrefix makes cosmos tokens easy to recognize, e.g, by secret scanners.
+	tokenPrefix = "cosmos_"
+
+	// SessionDuration is how long a session created by logging in remains valid.
+	SessionDuration = 24 * time.Hour
+
+	// minPasswordLength is the minimum length of a user's password.
+	minPasswordLength = 8
+
+	// Failed logins of a username from a client are throttled to slow down password guessing. Once more
+	// than loginFailuresAllowed logins in a row have failed, further logins of the username from the client
+	// are refused for a delay which starts at minLoginDelay and doubles with every further failure, up to
+	// maxLoginDelay. The account itself is never locked, so that others cannot lock users out.
+	loginFailuresAllowed = 5
+	minLoginDelay        = time.Second
+	maxLoginDelay        = 15 * time.Minute
+
+	// Failures which are older than maxLoginDelay are forgotten once the failures of
+	// maxLoginThrottled clients and usernames are kept track of.
+	maxLoginThrottled = 10000
+)
+
+// dummyPasswordHash is compared against when a user doesn't exist, so that logging in takes
+// as long for unknown usernames as it does for known ones.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("cosmos-dummy-password"), bcrypt.DefaultCost)
+
+// User represents a user who can log in with a username and password.
+// The role of the user applies to all syncs and endpoints. Users with the
+// none role can only access what they are given permissions for.
+type User struct {
+	ID           int       `json:"id"`
+	Username     string    `json:"username"`
+	Role         string    `json:"role"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// Validate performs some basic validation on the user object during create and update.
+func (u *User) Validate() error {
+	if u.Username == "" {
+		return Errorf(EINVALID, "Username required")
+	} else if !IsValidRole(u.Role) {
+		return Errorf(EINVALID, "Role must be one of 'none', 'viewer', 'operator' or 'admin'")
+	}
+	return nil
+}
+
+// UserFilter represents a user search filter.
+type UserFilter struct {
+	ID       *int    `json:"id"`
+	Username *string `json:"username"`
+
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// UserUpdate represents user fields that can be updated.
+type UserUpdate struct {
+	Password *string `json:"password"`
+	Role     *string `json:"role"`
+
+	// CurrentPassword must be given by users who change their own password.
+	CurrentPassword *string `json:"currentPassword"`
+}
+
+type UserService interface {
+	FindUserByID(ctx context.Context, id int) (*User, error)
+	FindUsers(ctx context.Context, filter UserFilter) ([]*User, int, error)
+	CreateUser(ctx context.Context, user *User) error
+	UpdateUser(ctx context.Context, id int, user *User) error
+	DeleteUser(ctx context.Context, id int) error
+}
+
+// Token represents an API token of a user. Sessions created by logging in with
+// a username and password are tokens which expire after SessionDuration.
+// Only the hash of a token is stored. The token itself is only returned when it is created.
+type Token struct {
+	ID         int       `json:"id"`
+	Name       string    `json:"name"`
+	UserID     int       `json:"userID"`
+	Hash       string    `json:"-"`
+	Session    bool      `json:"session"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// IsExpired returns true if the token has an expiry time which has passed.
+func (t *Token) IsExpired() bool {
+	return !t.ExpiresAt.IsZero() && time.Now().After(t.ExpiresAt)
+}
+
+// TokenFilter represents a token search filter.
+type TokenFilter struct {
+	ID      *int    `json:"id"`
+	UserID  *int    `json:"userID"`
+	Hash    *string `json:"-"`
+	Session *bool   `json:"session"`
+
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+type TokenService interface {
+	FindTokenByID(ctx context.Context, id int) (*Token, error)
+	FindTokens(ctx context.Context, filter TokenFilter) ([]*Token, int, error)
+	CreateToken(ctx context.Context, token *Token) error
+	TouchToken(ctx context.Context, id int, lastUsedAt time.Time) error
+	DeleteToken(ctx context.Context, id int) error
+	DeleteExpiredTokens(ctx context.Context) error
+}
+
+// HashToken returns the hash under which a token is stored.
+// Tokens are random, so a fast hash is sufficient.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateSecret returns a random, URL safe string.
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (a *App) CreateUser(ctx context.Context, user *User, password string) error {
+	// Users can only view syncs and runs unless they are given a role.
+	if user.Role == "" {
+		user.Role = RoleViewer
+	}
+
+	// Perform basic field validation.
+	if err := user.Validate(); err != nil {
+		return err
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = hash
+
+	return a.DBService.CreateUser(NewAuditContext(ctx, AuditActionCreate, AuditEntityUser, nil, user), user)
+}
+
+func (a *App) UpdateUser(ctx context.Context, id int, upd *UserUpdate) (*User, error) {
+	// Fetch the current user object from the database.
+	user, err := a.FindUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	before := *user
+
+	// Update fields if set. Users must confirm their current password to change it, so that
+	// a stolen session or API token cannot be used to take over the account.
+	if v := upd.Password; v != nil {
+		if current := UserFromContext(ctx); current != nil && current.ID == id {
+			if upd.CurrentPassword == nil || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(*upd.CurrentPassword)) != nil {
+				return nil, Errorf(EINVALID, "The current password is incorrect")
+			}
+		}
+		if user.PasswordHash, err = hashPassword(*v); err != nil {
+			return nil, err
+		}
+	}
+	if v := upd.Role; v != nil {
+		user.Role = *v
+	}
+
+	// Perform basic validation to make sure that the updates are correct.
+	if err := user.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := a.DBService.UpdateUser(NewAuditContext(ctx, AuditActionUpdate, AuditEntityUser, &before, user), id, user); err != nil {
+		return nil, err
+	}
+
+	// Log out the sessions that were started with the old password, except for the one making the change.
+	if upd.Password != nil {
+		if err := a.deleteSessions(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+// deleteSessions deletes the sessions of a user other than the session of the request.
+func (a *App) deleteSessions(ctx context.Context, userID int) error {
+	session := true
+	tokens, _, err := a.FindTokens(ctx, TokenFilter{UserID: &userID, Session: &session})
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		if current := TokenFromContext(ctx); current != nil && current.ID == token.ID {
+			continue
+		}
+		if err := a.DeleteToken(ctx, token.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *App) DeleteUser(ctx context.Context, id int) error {
+	user, err := a.FindUserByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return a.DBService.DeleteUser(NewAuditContext(ctx, AuditActionDelete, AuditEntityUser, user, nil), id)
+}
+
+// CreateInitialUser creates an admin with the given username if there are no users, so that the API can be accessed.
+// A random password is generated and returned if password is empty. An empty string is returned if a user already exists.
+func (a *App) CreateInitialUser(ctx context.Context, username, password string) (string, error) {
+	_, totalUsers, err := a.FindUsers(ctx, UserFilter{Limit: 1})
+	if err != nil {
+		return "", err
+	} else if totalUsers > 0 {
+		return "", nil
+	}
+
+	generated := password == ""
+	if generated {
+		if password, err = generateSecret(); err != nil {
+			return "", err
+		}
+	}
+
+	if err := a.CreateUser(ctx, &User{Username: username, Role: RoleAdmin}, password); err != nil {
+		return "", err
+	}
+
+	if !generated {
+		return "", nil
+	}
+	return password, nil
+}
+
+// CreateToken creates an API token for a user and returns it. This is the only time the token is available.
+func (a *App) CreateToken(ctx context.Context, token *Token) (string, error) {
+	if token.Name == "" {
+		return "", Errorf(EINVALID, "Token name required")
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return "", err
+	}
+
+	token.Hash = HashToken(tokenPrefix + secret)
+
+	// Sessions come and go with logins, so only API tokens are recorded.
+	auditCtx := ctx
+	if !token.Session {
+		auditCtx = NewAuditContext(ctx, AuditActionCreate, AuditEntityToken, nil, token)
+	}
+	if err := a.DBService.CreateToken(auditCtx, token); err != nil {
+		return "", err
+	}
+
+	return tokenPrefix + secret, nil
+}
+
+func (a *App) DeleteToken(ctx context.Context, id int) error {
+	token, err := a.FindTokenByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if token.Session {
+		return a.DBService.DeleteToken(ctx, id)
+	}
+	return a.DBService.DeleteToken(NewAuditContext(ctx, AuditActionDelete, AuditEntityToken, token, nil), id)
+}
+
+// Login verifies the username and password of a user and creates a new session for the user.
+// clientIP is the address that the login comes from, by which failed logins are throttled.
+func (a *App) Login(ctx context.Context, username, password, clientIP string) (string, *Token, error) {
+	key := loginKey{clientIP: clientIP, username: username}
+	if delay := a.loginThrottle.delay(key); delay > 0 {
+		return "", nil, Errorf(ETOOMANYREQUESTS, "Too many failed logins. Try again in %s", delay.Round(time.Second))
+	}
+
+	users, _, err := a.FindUsers(ctx, UserFilter{Username: &username})
+	if err != nil {
+		return "", nil, err
+	}
+
+	hash := dummyPasswordHash
+	if len(users) != 0 {
+		hash = []byte(users[0].PasswordHash)
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil || len(users) == 0 {
+		a.loginThrottle.fail(key)
+		return "", nil, Errorf(EUNAUTHORIZED, "Invalid username or password")
+	}
+	a.loginThrottle.succeed(key)
+
+	// Clean up the sessions which have expired since they are never used again.
+	if err := a.DeleteExpiredTokens(ctx); err != nil {
+		return "", nil, err
+	}
+
+	token := &Token{
+		Name:      "session",
+		UserID:    users[0].ID,
+		Session:   true,
+		ExpiresAt: time.Now().Add(SessionDuration),
+	}
+
+	secret, err := a.CreateToken(ctx, token)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return secret, token, nil
+}
+
+// loginThrottle keeps track of the consecutive failed logins of usernames from clients.
+type loginThrottle struct {
+	mu       sync.Mutex
+	failures map[loginKey]*loginFailures
+}
+
+type loginKey struct {
+	clientIP string
+	username string
+}
+
+type loginFailures struct {
+	count int
+	last  time.Time
+	until time.Time
+}
+
+// delay returns how long logins of the username from the client are refused because of failed logins.
+func (t *loginThrottle) delay(key loginKey) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if f, ok := t.failures[key]; ok {
+		if d := time.Until(f.until); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+func (t *loginThrottle) fail(key loginKey) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	if t.failures == nil {
+		t.failures = map[loginKey]*loginFailures{}
+	}
+
+	// Forget the failures which no longer matter once many clients and usernames are tracked.
+	if len(t.failures) >= maxLoginThrottled {
+		for k, f := range t.failures {
+			if now.Sub(f.last) > maxLoginDelay && now.After(f.until) {
+				delete(t.failures, k)
+			}
+		}
+	}
+
+	f, ok := t.failures[key]
+	if !ok || now.Sub(f.last) > maxLoginDelay {
+		f = &loginFailures{}
+		t.failures[key] = f
+	}
+	f.count++
+	f.last = now
+
+	if f.count > loginFailuresAllowed {
+		delay := maxLoginDelay
+		if n := f.count - loginFailuresAllowed - 1; n < 20 {
+			if d := minLoginDelay << n; d < maxLoginDelay {
+				delay = d
+			}
+		}
+		f.until = now.Add(delay)
+	}
+}
+
+func (t *loginThrottle) succeed(key loginKey) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.failures, key)
+}
+
+// Authenticate returns the user that a token (or session) belongs to.
+func (a *App) Authenticate(ctx context.Context, secret string) (*User, *Token, error) {
+	if secret == "" {
+		return nil, nil, Errorf(EUNAUTHORIZED, "Authentication required")
+	}
+
+	hash := HashToken(secret)
+	tokens, _, err := a.FindTokens(ctx, TokenFilter{Hash: &hash})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tokens) == 0 || tokens[0].IsExpired() {
+		return nil, nil, Errorf(EUNAUTHORIZED, "Invalid or expired token")
+	}
+	token := tokens[0]
+
+	user, err := a.FindUserByID(ctx, token.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Keep track of when the token was last used, but not on every request.
+	if time.Since(token.LastUsedAt) > time.Minute {
+		token.LastUsedAt = time.Now()
+		if err := a.TouchToken(ctx, token.ID, token.LastUsedAt); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return user, token, nil
+}
+
+func hashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", Errorf(EINVALID, "Password must be at least %d characters long", minPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// auth is the authenticated user and token of a request.
+type auth struct {
+	user  *User
+	token *Token
+}
+
+func NewAuthContext(ctx context.Context, user *User, token *Token) context.Context {
+	return context.WithValue(ctx, authKey, &auth{user: user, token: token})
+}
+
+// UserFromContext returns the authenticated user or nil if the request is not authenticated.
+func UserFromContext(ctx context.Context) *User {
+	if a, ok := ctx.Value(authKey).(*auth); ok {
+		return a.user
+	}
+	return nil
+}
+
+// TokenFromContext returns the token that the request was authenticated with or nil if the request is not authenticated.
+func TokenFromContext(ctx context.Context) *Token {
+	if a, ok := ctx.Value(authKey).(*auth); ok {
+		return a.token
+	}
+	return nil
+}
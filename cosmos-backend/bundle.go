@@ -0,0 +1,148 @@
+package cosmos
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"time"
+)
+
+// Paths of the files in a run bundle, relative to the root of the bundle.
+const (
+	BundleRun          = "run.json"
+	BundleImages       = "images.json"
+	BundleSrcConfig    = "config/source.json"
+	BundleDstConfig    = "config/destination.json"
+	BundleArtifactsDir = "artifacts"
+	BundleSrcCatalog   = "artifacts/source-catalog"
+	BundleDstCatalog   = "artifacts/destination-catalog"
+	BundleBeforeState  = "artifacts/before-state"
+	BundleReplayScript = "replay.sh"
+)
+
+// WriteRunBundle writes a gzipped tarball containing everything required to debug a run to w.
+//
+// The bundle contains all the artifacts of the run, the redacted configs of the endpoints,
+// the docker images (and their digests) used by the run, the run itself (including its options)
+// and a script which replays the docker commands of the run.
+func (a *App) WriteRunBundle(ctx context.Context, run *Run, w io.Writer) error {
+	artifactory, err := a.GetRunArtifactory(run)
+	if err != nil {
+		return err
+	}
+
+	files, err := a.ListArtifacts(artifactory)
+	if err != nil {
+		return err
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	root := fmt.Sprintf("run-%d", run.ID)
+	now := time.Now()
+
+	add := func(name string, mode int64, data []byte) error {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    path.Join(root, name),
+			Mode:    mode,
+			Size:    int64(len(data)),
+			ModTime: now,
+		}); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	addJSON := func(name string, v interface{}) error {
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		return add(name, 0644, b)
+	}
+
+	for _, f := range files {
+		// The configs are added separately after making sure that they are redacted.
+		if f.ID == ArtifactSrcConfig || f.ID == ArtifactDstConfig {
+			continue
+		}
+
+		var data []byte
+		name := f.Name
+		if IsLogArtifact(f.ID) {
+			name = ArtifactFileName(f.ID, f.Attempt)
+			data, err = a.GetArtifactAttemptData(artifactory, f.ID, f.Attempt)
+		} else {
+			data, err = a.GetArtifactData(artifactory, f.ID)
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := add(path.Join(BundleArtifactsDir, name), 0644, data); err != nil {
+			return err
+		}
+	}
+
+	// The configs are taken from the artifacts so that they match what the run used. Artifacts of
+	// older runs may contain secrets, so they are redacted again using the current connector specs.
+	configs := []struct {
+		id       int
+		name     string
+		endpoint *Endpoint
+	}{
+		{ArtifactSrcConfig, BundleSrcConfig, run.Sync.SourceEndpoint},
+		{ArtifactDstConfig, BundleDstConfig, run.Sync.DestinationEndpoint},
+	}
+	for _, c := range configs {
+		config := map[string]interface{}{}
+		if data, err := a.GetArtifactData(artifactory, c.id); err == nil {
+			if err := json.Unmarshal(data, &config); err != nil {
+				return err
+			}
+			a.RedactConfig(ctx, c.endpoint, config)
+		} else if ErrorCode(err) == ENOTFOUND {
+			config = a.RedactedConfig(ctx, c.endpoint)
+		} else {
+			return err
+		}
+		if err := addJSON(c.name, config); err != nil {
+			return err
+		}
+	}
+
+	images := a.Images(run.Sync)
+	for _, image := range images {
+		// Images which are not available locally are listed without digests.
+		image.Digests, _ = a.ImageDigests(ctx, image.Name)
+	}
+	if err := addJSON(BundleImages, images); err != nil {
+		return err
+	}
+
+	// The sync contains the unredacted endpoint configs and must not be part of the bundle.
+	r := *run
+	r.Sync = nil
+	if err := addJSON(BundleRun, &r); err != nil {
+		return err
+	}
+
+	beforeState := false
+	for _, f := range files {
+		if f.ID == ArtifactBeforeState {
+			beforeState = true
+		}
+	}
+	if err := add(BundleReplayScript, 0755, []byte(a.ReplayScript(run, beforeState))); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
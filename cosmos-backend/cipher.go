@@ -0,0 +1,356 @@
+package cosmos
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+// encryptedPrefix marks data which has been encrypted by a Cipher.
+// Encrypted data has the form <prefix><key ID>:<encrypted data key>:<encrypted data>.
+const encryptedPrefix = "enc:v1:"
+
+// keySize is the size of the AES-256 keys used for encryption.
+const keySize = 32
+
+var keyIDRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Cipher encrypts artifacts and secrets at rest using envelope encryption.
+//
+// Every artifact, every writer of a log artifact and every secret encrypts its data with a random data key.
+// The data key is encrypted with the primary key from the keyfile and stored along with the data.
+// Log artifacts are encrypted line by line so that they can be appended to and read from an offset.
+//
+// Keys are rotated by adding a new key to the keyfile and making it the primary key.
+// New data is encrypted with the primary key. Older keys must remain in the keyfile
+// for as long as there is data whose data keys were encrypted with them.
+//
+// Data which isn't encrypted is returned as is so that the data written
+// before encryption was enabled remains readable. A nil *Cipher doesn't encrypt.
+type Cipher struct {
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// keyfile is the format of the keyfile. Keys are base64 encoded 32 byte keys.
+//
+//	{"primary": "2021-09", "keys": {"2021-09": "...", "2021-03": "..."}}
+type keyfile struct {
+	Primary string            `json:"primary"`
+	Keys    map[string]string `json:"keys"`
+}
+
+// NewCipher returns a cipher which uses the keys in the given keyfile.
+func NewCipher(path string) (*Cipher, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var kf keyfile
+	if err := json.Unmarshal(b, &kf); err != nil {
+		return nil, fmt.Errorf("invalid keyfile. err: %w", err)
+	}
+
+	c := &Cipher{
+		primary: kf.Primary,
+		keys:    map[string]cipher.AEAD{},
+	}
+
+	for id, v := range kf.Keys {
+		if !keyIDRegex.MatchString(id) {
+			return nil, fmt.Errorf("invalid key ID %q", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(v)
+		if err != nil || len(key) != keySize {
+			return nil, fmt.Errorf("key %s must be %d base64 encoded bytes", id, keySize)
+		}
+		if c.keys[id], err = newAEAD(key); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := c.keys[c.primary]; !ok {
+		return nil, fmt.Errorf("primary key %q is not in the keyfile", kf.Primary)
+	}
+
+	return c, nil
+}
+
+// Seal encrypts data, e.g, the contents of an artifact.
+func (c *Cipher) Seal(plaintext []byte) ([]byte, error) {
+	if c == nil {
+		return plaintext, nil
+	}
+
+	key, err := c.newDataKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return key.seal(plaintext)
+}
+
+// Open decrypts data encrypted with Seal.
+func (c *Cipher) Open(data []byte) ([]byte, error) {
+	return c.open(data, map[string]cipher.AEAD{})
+}
+
+// OpenLines decrypts the lines of a log artifact.
+func (c *Cipher) OpenLines(data []byte) ([]byte, error) {
+	if c == nil && !bytes.Contains(data, []byte(encryptedPrefix)) {
+		return data, nil
+	}
+
+	// Lines written by the same writer share a data key. Decrypt it only once.
+	dataKeys := map[string]cipher.AEAD{}
+
+	out := make([]byte, 0, len(data))
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if !bytes.HasPrefix(line, []byte(encryptedPrefix)) {
+			out = append(out, line...)
+			continue
+		}
+		b, err := c.open(bytes.TrimSuffix(line, []byte("\n")), dataKeys)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b...)
+		if bytes.HasSuffix(line, []byte("\n")) {
+			out = append(out, '\n')
+		}
+	}
+
+	return out, nil
+}
+
+// OpenArtifact decrypts the contents of an artifact. The contents of log artifacts are decrypted line by line.
+func (c *Cipher) OpenArtifact(id int, data []byte) ([]byte, error) {
+	if IsLogArtifact(id) {
+		return c.OpenLines(data)
+	}
+	return c.Open(data)
+}
+
+// SealValue encrypts a JSON value, e.g, the value of a secret field of a form.
+func (c *Cipher) SealValue(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	b, err = c.Seal(b)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// OpenValue decrypts a value encrypted with SealValue. Values which aren't encrypted are returned as is.
+func (c *Cipher) OpenValue(v interface{}) (interface{}, error) {
+	s, ok := v.(string)
+	if !ok || !IsEncrypted(s) {
+		return v, nil
+	}
+
+	b, err := c.Open([]byte(s))
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(b, &value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// IsPrimary returns true if a value has been encrypted with the primary key.
+// Values which aren't are re-encrypted when the keys are rotated.
+func (c *Cipher) IsPrimary(v interface{}) bool {
+	s, ok := v.(string)
+	return ok && c != nil && strings.HasPrefix(s, encryptedPrefix+c.primary+":")
+}
+
+// IsEncrypted returns true if s has been encrypted by a Cipher.
+func IsEncrypted(s string) bool {
+	return strings.HasPrefix(s, encryptedPrefix)
+}
+
+// NewWriter returns a writer which encrypts every line written to w.
+func (c *Cipher) NewWriter(w io.WriteCloser) (io.WriteCloser, error) {
+	if c == nil {
+		return w, nil
+	}
+
+	key, err := c.newDataKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return &lineWriter{w: w, key: key}, nil
+}
+
+// open decrypts data using the data keys that have already been decrypted, if any.
+func (c *Cipher) open(data []byte, dataKeys map[string]cipher.AEAD) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(encryptedPrefix)) {
+		return data, nil
+	}
+	if c == nil {
+		return nil, errors.New("data is encrypted but no keyfile has been configured")
+	}
+
+	fields := strings.Split(string(data[len(encryptedPrefix):]), ":")
+	if len(fields) != 3 {
+		return nil, errors.New("malformed encrypted data")
+	}
+	keyID, encryptedKey, encryptedData := fields[0], fields[1], fields[2]
+
+	aead, ok := dataKeys[keyID+":"+encryptedKey]
+	if !ok {
+		kek, ok := c.keys[keyID]
+		if !ok {
+			return nil, fmt.Errorf("key %s is not in the keyfile", keyID)
+		}
+		b, err := base64.StdEncoding.DecodeString(encryptedKey)
+		if err != nil {
+			return nil, errors.New("malformed encrypted data")
+		}
+		key, err := open(kek, b)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decrypt data key. err: %w", err)
+		}
+		if aead, err = newAEAD(key); err != nil {
+			return nil, err
+		}
+		dataKeys[keyID+":"+encryptedKey] = aead
+	}
+
+	b, err := base64.StdEncoding.DecodeString(encryptedData)
+	if err != nil {
+		return nil, errors.New("malformed encrypted data")
+	}
+
+	plaintext, err := open(aead, b)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt data. err: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+// dataKey is a data key along with the header under which data encrypted with it is stored.
+type dataKey struct {
+	aead   cipher.AEAD
+	header string
+}
+
+// newDataKey generates a random data key and encrypts it with the primary key.
+func (c *Cipher) newDataKey() (*dataKey, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptedKey, err := seal(c.keys[c.primary], key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dataKey{
+		aead:   aead,
+		header: encryptedPrefix + c.primary + ":" + base64.StdEncoding.EncodeToString(encryptedKey) + ":",
+	}, nil
+}
+
+func (k *dataKey) seal(plaintext []byte) ([]byte, error) {
+	b, err := seal(k.aead, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(k.header + base64.StdEncoding.EncodeToString(b)), nil
+}
+
+// lineWriter encrypts every line written to it with the same data key.
+// Partial lines are buffered until they are completed or the writer is closed.
+type lineWriter struct {
+	w   io.WriteCloser
+	key *dataKey
+	buf []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if err := w.writeLine(w.buf[:i]); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[i+1:]
+	}
+
+	return len(p), nil
+}
+
+func (w *lineWriter) Close() error {
+	if len(w.buf) > 0 {
+		if err := w.writeLine(w.buf); err != nil {
+			return err
+		}
+		w.buf = nil
+	}
+	return w.w.Close()
+}
+
+func (w *lineWriter) writeLine(line []byte) error {
+	b, err := w.key.seal(line)
+	if err != nil {
+		return err
+	}
+	_, err = w.w.Write(append(b, '\n'))
+	return err
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext and prepends the random nonce to the ciphertext.
+func seal(aead cipher.AEAD, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// open decrypts data that was encrypted with seal.
+func open(aead cipher.AEAD, data []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+}
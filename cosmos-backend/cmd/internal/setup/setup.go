@@ -0,0 +1,108 @@
+// Package setup builds the services which are shared by cosmosd and temporald from the configuration.
+package setup
+
+import (
+	"cosmos"
+	"cosmos/config"
+	"cosmos/docker"
+	"cosmos/filesystem"
+	"cosmos/opentelemetry"
+	"cosmos/s3"
+	"cosmos/temporal"
+	"flag"
+	"fmt"
+	"os"
+
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/workflow"
+)
+
+type OpenCloser interface {
+	Open() error
+	Close() error
+}
+
+// LoadConfig reads the configuration file given by the -config flag or COSMOS_CONFIG.
+// Without one, the default configuration is used. Either way, environment variables
+// override the settings in it.
+func LoadConfig() (*config.Config, error) {
+	path := flag.String("config", os.Getenv("COSMOS_CONFIG"), "path to the TOML configuration file")
+	flag.Parse()
+	return config.Load(*path)
+}
+
+// NewSecretCipher returns the cipher which encrypts the secrets of endpoint configs
+// at rest with the keys in the secrets keyfile, or nil if it isn't configured.
+func NewSecretCipher(config *config.Config) (*cosmos.Cipher, error) {
+	keyfile := config.Secrets.Keyfile
+	if keyfile == "" {
+		return nil, nil
+	}
+
+	secretCipher, err := cosmos.NewCipher(keyfile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load the secret keyfile: %w", err)
+	}
+
+	return secretCipher, nil
+}
+
+// NewArtifactService returns the object store backed artifact service if an S3 endpoint
+// is configured and the local filesystem backed artifact service otherwise. Artifacts are
+// encrypted at rest with the keys in the artifacts keyfile if it is configured. The returned
+// OpenCloser is nil if the artifact service doesn't have to be opened.
+func NewArtifactService(config *config.Config) (cosmos.ArtifactService, OpenCloser, error) {
+	var artifactCipher *cosmos.Cipher
+	if keyfile := config.Artifacts.Keyfile; keyfile != "" {
+		var err error
+		if artifactCipher, err = cosmos.NewCipher(keyfile); err != nil {
+			return nil, nil, fmt.Errorf("cannot load the artifact keyfile: %w", err)
+		}
+	}
+
+	if config.Artifacts.S3.Endpoint == "" {
+		artifactService := filesystem.NewArtifactService()
+		artifactService.Dir = config.Artifacts.Dir
+		artifactService.HostDir = config.Artifacts.HostDir
+		artifactService.Cipher = artifactCipher
+		return artifactService, nil, nil
+	}
+
+	artifactService := s3.NewArtifactService(
+		config.Artifacts.S3.Endpoint,
+		config.Artifacts.S3.AccessKeyID,
+		config.Artifacts.S3.SecretAccessKey,
+		config.Artifacts.S3.Bucket,
+		config.Artifacts.S3.Secure,
+	)
+	artifactService.Dir = config.Artifacts.Dir
+	artifactService.HostDir = config.Artifacts.HostDir
+	artifactService.Cipher = artifactCipher
+
+	return artifactService, artifactService, nil
+}
+
+// NewCommandService returns the service which runs the docker commands of connectors.
+func NewCommandService(config *config.Config) *docker.CommandService {
+	commandService := docker.NewCommandService()
+	commandService.ScratchDir = config.Scratch.Dir
+	commandService.HostScratchDir = config.Scratch.HostDir
+	commandService.HostLocalDir = config.Connectors.LocalDir
+	return commandService
+}
+
+// NewTracerProvider returns the tracer provider of the daemon with the given service name.
+func NewTracerProvider(serviceName string, config *config.Config) *opentelemetry.TracerProvider {
+	return opentelemetry.NewTracerProvider(serviceName, config.Tracing.Exporter, config.Tracing.OTLPEndpoint, config.Tracing.File)
+}
+
+// NewTemporalClient returns a temporal client which propagates the trace
+// context of the workflows that it starts to their activities.
+func NewTemporalClient(config *config.Config, logger log.Logger) (client.Client, error) {
+	return client.NewClient(client.Options{
+		HostPort:           config.Temporal.HostPort,
+		Logger:             logger,
+		ContextPropagators: []workflow.ContextPropagator{temporal.NewTracePropagator()},
+	})
+}
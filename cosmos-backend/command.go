@@ -8,7 +8,25 @@ type CommandService interface {
 	Spec(ctx context.Context, connector *Connector) (*Message, error)
 	Check(ctx context.Context, connector *Connector, config interface{}) (*Message, error)
 	Discover(ctx context.Context, connector *Connector, config interface{}) (*Message, error)
-	Read(ctx context.Context, connector *Connector, empty bool) (<-chan interface{}, <-chan error)
-	Write(ctx context.Context, connector *Connector, in <-chan *Message) (<-chan interface{}, <-chan error)
-	Normalize(ctx context.Context, connector *Connector, basicNormalization bool) (<-chan interface{}, <-chan error)
+	Read(ctx context.Context, connector *Connector, config interface{}, empty bool) (<-chan interface{}, <-chan error)
+	ReadStream(ctx context.Context, connector *Connector, config interface{}, configuredCatalog interface{}) (<-chan interface{}, <-chan error)
+	Write(ctx context.Context, connector *Connector, config interface{}, in <-chan *Message) (<-chan interface{}, <-chan error)
+	Normalize(ctx context.Context, connector *Connector, config interface{}, basicNormalization bool) (<-chan interface{}, <-chan error)
+	Images(sync *Sync) []*Image
+	ImageDigests(ctx context.Context, image string) ([]string, error)
+	ReplayScript(run *Run, beforeState bool) string
+}
+
+// Image roles.
+const (
+	ImageRoleSource        = "source"
+	ImageRoleDestination   = "destination"
+	ImageRoleNormalization = "normalization"
+)
+
+// Image is a docker image that is run as part of a sync.
+type Image struct {
+	Role    string   `json:"role"`
+	Name    string   `json:"name"`
+	Digests []string `json:"digests"`
 }
@@ -20,9 +20,11 @@ var DestinationTypes = []string{
 	"other",
 }
 
-// Connector represents a source or destination connector.
+// Connector represents a source or destination connector. Connectors are shared by all workspaces
+// unless they are kept private to the workspace that they were created in.
 type Connector struct {
 	ID              int       `json:"id"`
+	WorkspaceID     *int      `json:"workspaceID"`
 	Name            string    `json:"name"`
 	Type            string    `json:"type"`
 	DockerImageName string    `json:"dockerImageName"`
@@ -90,6 +92,14 @@ type ConnectorService interface {
 }
 
 func (a *App) CreateConnector(ctx context.Context, connector *Connector) error {
+	// A private connector can only be created in the workspace of the request.
+	if connector.WorkspaceID != nil {
+		workspaceID := workspaceOf(ctx)
+		connector.WorkspaceID = &workspaceID
+	} else if err := authorizeShared(ctx); err != nil {
+		return err
+	}
+
 	// Perform basic field validation.
 	if err := connector.Validate(); err != nil {
 		return err
@@ -102,7 +112,7 @@ func (a *App) CreateConnector(ctx context.Context, connector *Connector) error {
 	}
 	connector.Spec = *msg
 
-	return a.DBService.CreateConnector(ctx, connector)
+	return a.DBService.CreateConnector(NewAuditContext(ctx, AuditActionCreate, AuditEntityConnector, nil, connector), connector)
 }
 
 func (a *App) UpdateConnector(ctx context.Context, id int, upd *ConnectorUpdate) (*Connector, error) {
@@ -111,6 +121,13 @@ func (a *App) UpdateConnector(ctx context.Context, id int, upd *ConnectorUpdate)
 	if err != nil {
 		return nil, err
 	}
+	before := *connector
+
+	if connector.WorkspaceID == nil {
+		if err := authorizeShared(ctx); err != nil {
+			return nil, err
+		}
+	}
 
 	// Update fields if set.
 	if v := upd.Name; v != nil {
@@ -138,9 +155,33 @@ func (a *App) UpdateConnector(ctx context.Context, id int, upd *ConnectorUpdate)
 	}
 	connector.Spec = *msg
 
-	if err := a.DBService.UpdateConnector(ctx, id, connector); err != nil {
+	if err := a.DBService.UpdateConnector(NewAuditContext(ctx, AuditActionUpdate, AuditEntityConnector, &before, connector), id, connector); err != nil {
 		return nil, err
 	}
 
 	return connector, nil
 }
+
+func (a *App) DeleteConnector(ctx context.Context, id int) error {
+	connector, err := a.FindConnectorByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if connector.WorkspaceID == nil {
+		if err := authorizeShared(ctx); err != nil {
+			return err
+		}
+	}
+
+	return a.DBService.DeleteConnector(NewAuditContext(ctx, AuditActionDelete, AuditEntityConnector, connector, nil), id)
+}
+
+// authorizeShared returns an EFORBIDDEN error unless the authenticated user is an admin of all workspaces,
+// since shared connectors are used by all of them. Admins of a single workspace can only manage its private connectors.
+func authorizeShared(ctx context.Context) error {
+	if user := UserFromContext(ctx); user != nil && !HasRole(user.Role, RoleAdmin) {
+		return Errorf(EFORBIDDEN, "Only admins of all workspaces can manage shared connectors")
+	}
+	return nil
+}
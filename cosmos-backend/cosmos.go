@@ -16,14 +16,27 @@ type DBService interface {
 	EndpointService
 	SyncService
 	RunService
+	UserService
+	TokenService
+	PermissionService
+	AuditService
+	WorkspaceService
 }
 
 type App struct {
 	DBService
 	CommandService
 	MessageService
+	ExpressionService
+	SecretResolver
 	ArtifactService
 	SchedulerService
 	WorkerService
+	MetricsService
 	Logger
+
+	// SecretCipher decrypts the secret values of endpoint configs which are encrypted at rest.
+	SecretCipher *Cipher
+
+	loginThrottle loginThrottle
 }
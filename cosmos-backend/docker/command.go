@@ -17,20 +17,38 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	jsoniter "github.com/json-iterator/go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var json = jsoniter.ConfigDefault
 
+var tracer = otel.Tracer("cosmos/docker")
+
 var _ cosmos.CommandService = (*CommandService)(nil)
 
 type CommandService struct {
+	// ScratchDir is the directory in which ephemeral connector configs are written.
+	ScratchDir string
+
+	// HostScratchDir and HostLocalDir are the scratch directory and the directory that is mounted
+	// at /local in connector containers as seen by the docker daemon. For Docker-in-Docker, these
+	// are the paths on the host.
+	HostScratchDir string
+	HostLocalDir   string
+
 	*cosmos.App
 }
 
 func NewCommandService() *CommandService {
-	return &CommandService{}
+	return &CommandService{
+		ScratchDir: cosmos.ScratchSpace,
+	}
 }
 
 const (
@@ -60,7 +78,7 @@ func (s *CommandService) Discover(ctx context.Context, connector *cosmos.Connect
 	return s.Runner(ctx, connector, config, cosmos.MessageTypeCatalog)
 }
 
-func (s *CommandService) Read(ctx context.Context, connector *cosmos.Connector, empty bool) (<-chan interface{}, <-chan error) {
+func (s *CommandService) Read(ctx context.Context, connector *cosmos.Connector, config interface{}, empty bool) (<-chan interface{}, <-chan error) {
 	out := make(chan interface{}, 100)
 	errc := make(chan error, 1)
 
@@ -77,55 +95,97 @@ func (s *CommandService) Read(ctx context.Context, connector *cosmos.Connector,
 		defer close(out)
 
 		artifactory := cosmos.ArtifactoryFromContext(ctx)
-		configFile := s.App.GetArtifactPath(artifactory, cosmos.ArtifactSrcConfig)
+		configFile, removeConfigFile, err := s.getConfigFile(config)
+		if err != nil {
+			errc <- fmt.Errorf("failed to create config file in read command. err: %w", err)
+			return
+		}
+		defer removeConfigFile()
+
 		configuredCatalogFile := s.App.GetArtifactPath(artifactory, cosmos.ArtifactSrcCatalog)
 		stateFile := s.App.GetArtifactPath(artifactory, cosmos.ArtifactBeforeState)
 
-		dockerImage := connector.DockerImageName + ":" + connector.DockerImageTag
-		cmdString := prepareDockerCmd("read", false, dockerImage, nil, configFile, configuredCatalogFile, stateFile)
-		s.sendOutput(ctx, out, fmt.Sprintf("Docker command: docker %s", cmdString))
+		errc <- s.read(ctx, out, connector, configFile, configuredCatalogFile, stateFile)
+	}()
 
-		cmd := exec.CommandContext(ctx, "docker", strings.Split(cmdString, " ")...)
-		stdout, err := cmd.StdoutPipe()
+	return out, errc
+}
+
+// ReadStream runs the read command of a source connector with the given configured catalog and no state.
+// It is used to preview the records of a stream. The read is stopped by cancelling the context.
+func (s *CommandService) ReadStream(ctx context.Context, connector *cosmos.Connector, config interface{}, configuredCatalog interface{}) (<-chan interface{}, <-chan error) {
+	out := make(chan interface{}, 100)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer recoverFromPanic()
+		defer close(out)
+
+		configFile, removeConfigFile, err := s.getConfigFile(config)
 		if err != nil {
-			errc <- fmt.Errorf("failed to get stdout pipe in read command. err: %w", err)
+			errc <- fmt.Errorf("failed to create config file in read command. err: %w", err)
 			return
 		}
-		stderr, err := cmd.StderrPipe()
+		defer removeConfigFile()
+
+		configuredCatalogFile, removeConfiguredCatalogFile, err := s.getConfigFile(configuredCatalog)
 		if err != nil {
-			errc <- fmt.Errorf("failed to get stderr pipe in read command. err: %w", err)
-			return
-		}
-		if err := cmd.Start(); err != nil {
-			errc <- fmt.Errorf("failed to start read command. err: %w", err)
+			errc <- fmt.Errorf("failed to create configured catalog file in read command. err: %w", err)
 			return
 		}
+		defer removeConfiguredCatalogFile()
 
-		// If the scan terminated prematurely with an error, kill the command.
-		// cmd.Wait() will get the killed error.
-		scanErr := s.scanOutput(ctx, stdout, stderr, out)
-		if scanErr != nil {
-			if err := cmd.Process.Kill(); err != nil {
-				panic(fmt.Sprintf("Unable to kill read command. err: %s", err))
-			}
-		}
+		errc <- s.read(ctx, out, connector, configFile, configuredCatalogFile, nil)
+	}()
 
-		if err := cmd.Wait(); err != nil {
-			if scanErr != nil {
-				errc <- fmt.Errorf("read command scanner failed with err: %w", scanErr)
-			} else {
-				errc <- fmt.Errorf("read command failed with err: %w", err)
-			}
-			return
+	return out, errc
+}
+
+func (s *CommandService) read(ctx context.Context, out chan<- interface{}, connector *cosmos.Connector, configFile, configuredCatalogFile, stateFile *string) error {
+	dockerImage := connector.DockerImageName + ":" + connector.DockerImageTag
+	ctx, finish := s.startCommand(ctx, dockerImage, "read")
+
+	cmdString := s.prepareDockerCmd("read", false, dockerImage, nil, configFile, configuredCatalogFile, stateFile)
+
+	cmd := exec.CommandContext(ctx, "docker", strings.Split(cmdString, " ")...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return finish(fmt.Errorf("failed to get stdout pipe in read command. err: %w", err))
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return finish(fmt.Errorf("failed to get stderr pipe in read command. err: %w", err))
+	}
+	if err := cmd.Start(); err != nil {
+		return finish(fmt.Errorf("failed to start read command. err: %w", err))
+	}
+	s.sendStartEvent(ctx, out, cmdString)
+
+	// If the scan terminated prematurely with an error, kill the command.
+	// cmd.Wait() will get the killed error.
+	scanErr := s.scanOutput(ctx, stdout, stderr, out)
+	if scanErr != nil {
+		if err := cmd.Process.Kill(); err != nil {
+			panic(fmt.Sprintf("Unable to kill read command. err: %s", err))
 		}
+	}
 
-		errc <- nil
-	}()
+	err = cmd.Wait()
+	s.sendExitEvent(ctx, out, cmd.ProcessState)
+	if err != nil {
+		if scanErr != nil {
+			return finish(fmt.Errorf("read command scanner failed with err: %w", scanErr))
+		}
+		return finish(fmt.Errorf("read command failed with err: %w", err))
+	}
 
-	return out, errc
+	return finish(nil)
 }
 
-func (s *CommandService) Write(ctx context.Context, connector *cosmos.Connector, in <-chan *cosmos.Message) (<-chan interface{}, <-chan error) {
+func (s *CommandService) Write(ctx context.Context, connector *cosmos.Connector, config interface{}, in <-chan *cosmos.Message) (<-chan interface{}, <-chan error) {
+	dockerImage := connector.DockerImageName + ":" + connector.DockerImageTag
+	ctx, finish := s.startCommand(ctx, dockerImage, "write")
+
 	out := make(chan interface{}, 100)
 	errc := make(chan error, 1)
 
@@ -136,33 +196,38 @@ func (s *CommandService) Write(ctx context.Context, connector *cosmos.Connector,
 		var wg sync.WaitGroup
 
 		artifactory := cosmos.ArtifactoryFromContext(ctx)
-		configFile := s.App.GetArtifactPath(artifactory, cosmos.ArtifactDstConfig)
+		configFile, removeConfigFile, err := s.getConfigFile(config)
+		if err != nil {
+			errc <- finish(fmt.Errorf("failed to create config file in write command. err: %w", err))
+			return
+		}
+		defer removeConfigFile()
+
 		configuredCatalogFile := s.App.GetArtifactPath(artifactory, cosmos.ArtifactDstCatalog)
 
-		dockerImage := connector.DockerImageName + ":" + connector.DockerImageTag
-		cmdString := prepareDockerCmd("write", true, dockerImage, nil, configFile, configuredCatalogFile, nil)
-		s.sendOutput(ctx, out, fmt.Sprintf("Docker command: docker %s", cmdString))
+		cmdString := s.prepareDockerCmd("write", true, dockerImage, nil, configFile, configuredCatalogFile, nil)
 
 		cmd := exec.CommandContext(ctx, "docker", strings.Split(cmdString, " ")...)
 		stdin, err := cmd.StdinPipe()
 		if err != nil {
-			errc <- fmt.Errorf("failed to get stdin pipe in write command. err: %w", err)
+			errc <- finish(fmt.Errorf("failed to get stdin pipe in write command. err: %w", err))
 			return
 		}
 		stdout, err := cmd.StdoutPipe()
 		if err != nil {
-			errc <- fmt.Errorf("failed to get stdout pipe in write command. err: %w", err)
+			errc <- finish(fmt.Errorf("failed to get stdout pipe in write command. err: %w", err))
 			return
 		}
 		stderr, err := cmd.StderrPipe()
 		if err != nil {
-			errc <- fmt.Errorf("failed to get stderr pipe in write command. err: %w", err)
+			errc <- finish(fmt.Errorf("failed to get stderr pipe in write command. err: %w", err))
 			return
 		}
 		if err := cmd.Start(); err != nil {
-			errc <- fmt.Errorf("failed to start write command. err: %w", err)
+			errc <- finish(fmt.Errorf("failed to start write command. err: %w", err))
 			return
 		}
+		s.sendStartEvent(ctx, out, cmdString)
 
 		wg.Add(1)
 		go func() {
@@ -195,22 +260,26 @@ func (s *CommandService) Write(ctx context.Context, connector *cosmos.Connector,
 
 		wg.Wait()
 
-		if err := cmd.Wait(); err != nil {
+		err = cmd.Wait()
+		s.sendExitEvent(ctx, out, cmd.ProcessState)
+		if err != nil {
 			if scanErr != nil {
-				errc <- fmt.Errorf("write command scanner failed with err: %w", scanErr)
+				errc <- finish(fmt.Errorf("write command scanner failed with err: %w", scanErr))
 			} else {
-				errc <- fmt.Errorf("write command failed with err: %w", err)
+				errc <- finish(fmt.Errorf("write command failed with err: %w", err))
 			}
 			return
 		}
 
-		errc <- nil
+		errc <- finish(nil)
 	}()
 
 	return out, errc
 }
 
-func (s *CommandService) Normalize(ctx context.Context, connector *cosmos.Connector, basicNormalization bool) (<-chan interface{}, <-chan error) {
+func (s *CommandService) Normalize(ctx context.Context, connector *cosmos.Connector, config interface{}, basicNormalization bool) (<-chan interface{}, <-chan error) {
+	ctx, finish := s.startCommand(ctx, NormalizationDockerImage, "normalize")
+
 	out := make(chan interface{}, 100)
 	errc := make(chan error, 1)
 
@@ -221,32 +290,38 @@ func (s *CommandService) Normalize(ctx context.Context, connector *cosmos.Connec
 		// check whether normalization has to be performed.
 		if !basicNormalization {
 			s.sendOutput(ctx, out, "Normalization is not available or is disabled. Skipping.")
-			errc <- nil
+			errc <- finish(nil)
 			return
 		}
 
 		artifactory := cosmos.ArtifactoryFromContext(ctx)
-		configFile := s.App.GetArtifactPath(artifactory, cosmos.ArtifactDstConfig)
+		configFile, removeConfigFile, err := s.getConfigFile(config)
+		if err != nil {
+			errc <- finish(fmt.Errorf("failed to create config file in normalization command. err: %w", err))
+			return
+		}
+		defer removeConfigFile()
+
 		configuredCatalogFile := s.App.GetArtifactPath(artifactory, cosmos.ArtifactDstCatalog)
 
-		cmdString := prepareDockerCmd("run", false, NormalizationDockerImage, &connector.DestinationType, configFile, configuredCatalogFile, nil)
-		s.sendOutput(ctx, out, fmt.Sprintf("Docker command: docker %s", cmdString))
+		cmdString := s.prepareDockerCmd("run", false, NormalizationDockerImage, &connector.DestinationType, configFile, configuredCatalogFile, nil)
 
 		cmd := exec.CommandContext(ctx, "docker", strings.Split(cmdString, " ")...)
 		stdout, err := cmd.StdoutPipe()
 		if err != nil {
-			errc <- fmt.Errorf("failed to get stdout pipe in normalization command. err: %w", err)
+			errc <- finish(fmt.Errorf("failed to get stdout pipe in normalization command. err: %w", err))
 			return
 		}
 		stderr, err := cmd.StderrPipe()
 		if err != nil {
-			errc <- fmt.Errorf("failed to get stderr pipe in normalization command. err: %w", err)
+			errc <- finish(fmt.Errorf("failed to get stderr pipe in normalization command. err: %w", err))
 			return
 		}
 		if err := cmd.Start(); err != nil {
-			errc <- fmt.Errorf("failed to start normalization command. err: %w", err)
+			errc <- finish(fmt.Errorf("failed to start normalization command. err: %w", err))
 			return
 		}
+		s.sendStartEvent(ctx, out, cmdString)
 
 		// If the scan terminated prematurely with an error, kill the command.
 		// cmd.Wait() will get the killed error.
@@ -257,16 +332,18 @@ func (s *CommandService) Normalize(ctx context.Context, connector *cosmos.Connec
 			}
 		}
 
-		if err := cmd.Wait(); err != nil {
+		err = cmd.Wait()
+		s.sendExitEvent(ctx, out, cmd.ProcessState)
+		if err != nil {
 			if scanErr != nil {
-				errc <- fmt.Errorf("normalization command scanner failed with err: %w", scanErr)
+				errc <- finish(fmt.Errorf("normalization command scanner failed with err: %w", scanErr))
 			} else {
-				errc <- fmt.Errorf("normalization command failed with err: %w", err)
+				errc <- finish(fmt.Errorf("normalization command failed with err: %w", err))
 			}
 			return
 		}
 
-		errc <- nil
+		errc <- finish(nil)
 	}()
 
 	return out, errc
@@ -279,50 +356,49 @@ func (s *CommandService) Runner(
 	messageType string,
 ) (*cosmos.Message, error) {
 
-	var configFile *os.File
-	var configFileName string
-	var err error
-
-	if config != nil {
-		configFile, err = getTempFile(config)
-		if err != nil {
-			return nil, err
-		}
-		defer os.Remove(configFile.Name())
-		configFileName = configFile.Name()
-
-		// For Docker-in-Docker, we have to return the path as it would be on the host.
-		configFileName = strings.TrimPrefix(configFileName, cosmos.ScratchSpace)
-		configFileName = filepath.Join(os.Getenv("SCRATCH_SPACE"), configFileName)
-	}
-
-	dockerImage := connector.DockerImageName + ":" + connector.DockerImageTag
-	var cmd string
+	var command string
 
 	switch messageType {
 	case cosmos.MessageTypeSpec:
-		cmd = prepareDockerCmd("spec", false, dockerImage, nil, nil, nil, nil)
+		command = "spec"
 	case cosmos.MessageTypeConnectionStatus:
-		cmd = prepareDockerCmd("check", false, dockerImage, nil, &configFileName, nil, nil)
+		command = "check"
 	case cosmos.MessageTypeCatalog:
-		cmd = prepareDockerCmd("discover", false, dockerImage, nil, &configFileName, nil, nil)
+		command = "discover"
 	default:
 		panic("Unhandled message type in docker runner")
 	}
 
+	dockerImage := connector.DockerImageName + ":" + connector.DockerImageTag
+	ctx, finish := s.startCommand(ctx, dockerImage, command)
+
+	var configFile *string
+
+	if config != nil {
+		var removeConfigFile func()
+		var err error
+		configFile, removeConfigFile, err = s.getConfigFile(config)
+		if err != nil {
+			return nil, finish(err)
+		}
+		defer removeConfigFile()
+	}
+
+	cmd := s.prepareDockerCmd(command, false, dockerImage, nil, configFile, nil, nil)
+
 	out, err := exec.CommandContext(ctx, "docker", strings.Split(cmd, " ")...).Output()
 	if err != nil {
-		return nil, fmt.Errorf("failed to run %s command on docker image %s err=%w", messageType, dockerImage, err)
+		return nil, finish(fmt.Errorf("failed to run %s command on docker image %s err=%w", messageType, dockerImage, err))
 	}
 
 	for _, row := range bytes.Split(out, []byte("\n")) {
 		msg, err := s.App.CreateMessage(ctx, row)
 		if err == nil && msg.Type == messageType {
-			return msg, nil
+			return msg, finish(nil)
 		}
 	}
 
-	return nil, fmt.Errorf("docker runner failed to find any %s messages", messageType)
+	return nil, finish(fmt.Errorf("docker runner failed to find any %s messages", messageType))
 }
 
 func (s *CommandService) scanOutput(ctx context.Context, stdout io.ReadCloser, stderr io.ReadCloser, out chan<- interface{}) error {
@@ -361,7 +437,64 @@ func (s *CommandService) sendOutput(ctx context.Context, out chan<- interface{},
 	}
 }
 
-func getTempFile(contents interface{}) (tmpFile *os.File, err error) {
+// sendStartEvent sends the lifecycle event for a started container.
+func (s *CommandService) sendStartEvent(ctx context.Context, out chan<- interface{}, cmdString string) {
+	s.sendOutput(ctx, out, &cosmos.Event{
+		Type:    cosmos.EventContainerStart,
+		Message: fmt.Sprintf("Docker command: docker %s", cmdString),
+	})
+}
+
+// sendExitEvent sends the lifecycle event for an exited container.
+func (s *CommandService) sendExitEvent(ctx context.Context, out chan<- interface{}, state *os.ProcessState) {
+	if state == nil {
+		return
+	}
+	s.sendOutput(ctx, out, &cosmos.Event{
+		Type:    cosmos.EventContainerExit,
+		Message: fmt.Sprintf("Container exited with code %d", state.ExitCode()),
+	})
+}
+
+// startCommand starts the span of a docker command of a connector. The returned function must be
+// called with the error (if any) that the command failed with. It ends the span, records the
+// metrics of the command and returns the error.
+func (s *CommandService) startCommand(ctx context.Context, image, command string) (context.Context, func(error) error) {
+	start := time.Now()
+	ctx, span := tracer.Start(ctx, "docker "+command, trace.WithAttributes(
+		attribute.String("connector", image),
+		attribute.String("command", command),
+	))
+
+	return ctx, func(err error) error {
+		s.App.ObserveCommand(image, command, time.Since(start), err)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+		return err
+	}
+}
+
+// getConfigFile writes a connector config to an ephemeral file in the scratch space which is readable
+// only by its owner. Configs contain secrets and must never be written to the artifacts. It returns
+// the path of the file as seen by the docker daemon and a function which removes the file.
+func (s *CommandService) getConfigFile(config interface{}) (*string, func(), error) {
+	tmpFile, err := getTempFile(s.ScratchDir, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpFile.Close()
+
+	// For Docker-in-Docker, we have to return the path as it would be on the host.
+	path := strings.TrimPrefix(tmpFile.Name(), s.ScratchDir)
+	path = filepath.Join(s.HostScratchDir, path)
+
+	return &path, func() { os.Remove(tmpFile.Name()) }, nil
+}
+
+func getTempFile(dir string, contents interface{}) (tmpFile *os.File, err error) {
 	defer func() {
 		if err != nil && tmpFile != nil {
 			os.Remove(tmpFile.Name())
@@ -369,7 +502,7 @@ func getTempFile(contents interface{}) (tmpFile *os.File, err error) {
 		}
 	}()
 
-	tmpFile, err = ioutil.TempFile(cosmos.ScratchSpace, "cosmos-")
+	tmpFile, err = ioutil.TempFile(dir, "cosmos-")
 	if err != nil {
 		return nil, err
 	}
@@ -386,7 +519,19 @@ func getTempFile(contents interface{}) (tmpFile *os.File, err error) {
 	return tmpFile, nil
 }
 
-func prepareDockerCmd(
+func (s *CommandService) prepareDockerCmd(
+	cmd string,
+	interactive bool,
+	dockerImage string,
+	destinationType *string,
+	configFile *string,
+	configuredCatalogFile *string,
+	stateFile *string,
+) string {
+	return prepareDockerCmdWithLocalDir(cmd, interactive, dockerImage, destinationType, configFile, configuredCatalogFile, stateFile, s.HostLocalDir)
+}
+
+func prepareDockerCmdWithLocalDir(
 	cmd string,
 	interactive bool,
 	dockerImage string,
@@ -394,6 +539,7 @@ func prepareDockerCmd(
 	configFile *string,
 	configuredCatalogFile *string,
 	stateFile *string,
+	localDir string,
 ) string {
 
 	builder := strings.Builder{}
@@ -417,7 +563,7 @@ func prepareDockerCmd(
 		builder.WriteString(fmt.Sprintf(volMount, *stateFile, "/tmp/cosmos-state"))
 	}
 
-	builder.WriteString(fmt.Sprintf(volMount, os.Getenv("LOCAL_DIR"), "/local"))
+	builder.WriteString(fmt.Sprintf(volMount, localDir, "/local"))
 
 	builder.WriteString(fmt.Sprintf("%s %s ", dockerImage, cmd))
 
@@ -0,0 +1,97 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"cosmos"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+const replayScriptHeader = `#!/bin/sh
+# Replays the docker commands of cosmos run %d of sync %d %s.
+#
+# The secrets in the configs have been redacted. Fill them in before running
+# this script. Records are piped from the source to the destination as is,
+# i.e, field selection, field policies, filters and namespace mapping are not
+# applied.
+set -e
+
+BUNDLE="$(cd "$(dirname "$0")" && pwd)"
+LOCAL_DIR="${LOCAL_DIR:-/tmp/cosmos/local}"
+
+`
+
+func (s *CommandService) Images(sync *cosmos.Sync) []*cosmos.Image {
+	images := []*cosmos.Image{
+		{Role: cosmos.ImageRoleSource, Name: connectorImage(sync.SourceEndpoint.Connector)},
+		{Role: cosmos.ImageRoleDestination, Name: connectorImage(sync.DestinationEndpoint.Connector)},
+	}
+	if sync.BasicNormalization {
+		images = append(images, &cosmos.Image{Role: cosmos.ImageRoleNormalization, Name: NormalizationDockerImage})
+	}
+	return images
+}
+
+// ImageDigests returns the repository digests of a docker image that is available locally.
+func (s *CommandService) ImageDigests(ctx context.Context, image string) ([]string, error) {
+	out, err := exec.CommandContext(ctx, "docker", "image", "inspect", "--format", "{{json .RepoDigests}}", image).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect docker image %s. err: %w", image, err)
+	}
+
+	digests := []string{}
+	if err := json.Unmarshal(bytes.TrimSpace(out), &digests); err != nil {
+		return nil, err
+	}
+
+	return digests, nil
+}
+
+// ReplayScript returns a shell script which runs the docker commands of a run
+// using the files in the run bundle. The state is passed to the source only if
+// the bundle has the state the run started with.
+func (s *CommandService) ReplayScript(run *cosmos.Run, beforeState bool) string {
+	sync := run.Sync
+	builder := strings.Builder{}
+
+	// The name is quoted so that newlines or other control characters in it are escaped
+	// and can't end the comment.
+	builder.WriteString(fmt.Sprintf(replayScriptHeader, run.ID, sync.ID, strconv.Quote(sync.Name)))
+
+	bundlePath := func(path string) *string {
+		p := `"$BUNDLE/` + path + `"`
+		return &p
+	}
+
+	var stateFile *string
+	if beforeState {
+		stateFile = bundlePath(cosmos.BundleBeforeState)
+	}
+
+	read := prepareDockerCmdWithLocalDir("read", false, connectorImage(sync.SourceEndpoint.Connector), nil,
+		bundlePath(cosmos.BundleSrcConfig), bundlePath(cosmos.BundleSrcCatalog), stateFile, `"$LOCAL_DIR"`)
+	write := prepareDockerCmdWithLocalDir("write", true, connectorImage(sync.DestinationEndpoint.Connector), nil,
+		bundlePath(cosmos.BundleDstConfig), bundlePath(cosmos.BundleDstCatalog), nil, `"$LOCAL_DIR"`)
+
+	if run.Options.WipeDestination {
+		builder.WriteString("# The run wiped the destination, so no records are read from the source.\n")
+		builder.WriteString(fmt.Sprintf("docker %s < /dev/null\n", write))
+	} else {
+		builder.WriteString(fmt.Sprintf("docker %s \\\n  | grep -E '\"type\" *: *\"(RECORD|STATE)\"' \\\n  | docker %s\n", read, write))
+	}
+
+	if sync.BasicNormalization {
+		normalize := prepareDockerCmdWithLocalDir("run", false, NormalizationDockerImage, &sync.DestinationEndpoint.Connector.DestinationType,
+			bundlePath(cosmos.BundleDstConfig), bundlePath(cosmos.BundleDstCatalog), nil, `"$LOCAL_DIR"`)
+		builder.WriteString(fmt.Sprintf("\ndocker %s\n", normalize))
+	}
+
+	return builder.String()
+}
+
+func connectorImage(connector *cosmos.Connector) string {
+	return connector.DockerImageName + ":" + connector.DockerImageTag
+}
@@ -2,12 +2,14 @@ package cosmos
 
 import (
 	"context"
+	"strings"
 	"time"
 )
 
 // EndPoint represents a "Connector" that has been configured for a particular endpoint.
 type Endpoint struct {
 	ID             int        `json:"id"`
+	WorkspaceID    int        `json:"workspaceID"`
 	Name           string     `json:"name"`
 	Type           string     `json:"type"`
 	ConnectorID    int        `json:"connectorID"`
@@ -27,6 +29,14 @@ func (e *Endpoint) Validate() error {
 	} else if e.ConnectorID == 0 {
 		return Errorf(EINVALID, "A connector must be selected")
 	}
+
+	// References to external secrets are only resolved in secret fields, since the values of other
+	// fields are returned by the API.
+	for _, field := range e.Config.Spec {
+		if _, _, ok := ParseSecretRef(field.Value); ok && !field.Secret {
+			return Errorf(EINVALID, "Field %s: secrets can only be referenced in secret fields", strings.Join(field.Path, "."))
+		}
+	}
 	return nil
 }
 
@@ -40,6 +50,9 @@ type EndpointFilter struct {
 	Name *string `json:"name"`
 	Type *string `json:"type"`
 
+	// IDs restricts the endpoints to those that a user has permissions for.
+	IDs []int `json:"-"`
+
 	Offset int `json:"offset"`
 	Limit  int `json:"limit"`
 }
@@ -58,12 +71,20 @@ func (a *App) CreateEndpoint(ctx context.Context, endpoint *Endpoint) error {
 		return err
 	}
 
+	endpoint.WorkspaceID = workspaceOf(ctx)
+
 	connector, err := a.FindConnectorByID(ctx, endpoint.ConnectorID)
 	if err != nil {
 		return err
 	}
+	if connector.WorkspaceID != nil && *connector.WorkspaceID != endpoint.WorkspaceID {
+		return Errorf(EINVALID, "The connector is private to another workspace")
+	}
 
-	config := endpoint.Config.ToSpec()
+	config, err := a.ConnectorConfig(ctx, &endpoint.Config)
+	if err != nil {
+		return err
+	}
 
 	if err := a.Validate(ctx, config, &connector.Spec); err != nil {
 		return err
@@ -90,7 +111,7 @@ func (a *App) CreateEndpoint(ctx context.Context, endpoint *Endpoint) error {
 
 	endpoint.Connector = connector
 
-	return a.DBService.CreateEndpoint(ctx, endpoint)
+	return a.DBService.CreateEndpoint(NewAuditContext(ctx, AuditActionCreate, AuditEntityEndpoint, nil, endpoint), endpoint)
 }
 
 func (a *App) UpdateEndpoint(ctx context.Context, id int, upd *EndpointUpdate) (*Endpoint, error) {
@@ -99,12 +120,14 @@ func (a *App) UpdateEndpoint(ctx context.Context, id int, upd *EndpointUpdate) (
 	if err != nil {
 		return nil, err
 	}
+	before := *endpoint
 
 	// Update fields if set.
 	if v := upd.Name; v != nil {
 		endpoint.Name = *v
 	}
 	if v := upd.Config; v != nil {
+		v.KeepSecrets(&endpoint.Config)
 		endpoint.Config = *v
 	}
 
@@ -113,7 +136,10 @@ func (a *App) UpdateEndpoint(ctx context.Context, id int, upd *EndpointUpdate) (
 		return nil, err
 	}
 
-	config := endpoint.Config.ToSpec()
+	config, err := a.ConnectorConfig(ctx, &endpoint.Config)
+	if err != nil {
+		return nil, err
+	}
 
 	if err := a.Validate(ctx, config, &endpoint.Connector.Spec); err != nil {
 		return nil, err
@@ -132,33 +158,116 @@ func (a *App) UpdateEndpoint(ctx context.Context, id int, upd *EndpointUpdate) (
 		return nil, Errorf(EINVALID, "The configuration provided is invalid. %s", connectionError)
 	}
 
-	if err := a.DBService.UpdateEndpoint(ctx, id, endpoint); err != nil {
+	if err := a.DBService.UpdateEndpoint(NewAuditContext(ctx, AuditActionUpdate, AuditEntityEndpoint, &before, endpoint), id, endpoint); err != nil {
 		return nil, err
 	}
 
 	return endpoint, nil
 }
 
-func (a *App) RediscoverEndpoint(ctx context.Context, id int) error {
-	// Fetch the current endpoint object from the database.
+func (a *App) DeleteEndpoint(ctx context.Context, id int) error {
 	endpoint, err := a.FindEndpointByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	config := endpoint.Config.ToSpec()
+	return a.DBService.DeleteEndpoint(NewAuditContext(ctx, AuditActionDelete, AuditEntityEndpoint, endpoint, nil), id)
+}
+
+// SyncFailure describes a sync that could not be updated.
+type SyncFailure struct {
+	SyncID   int    `json:"syncID"`
+	SyncName string `json:"syncName"`
+	Error    string `json:"error"`
+
+	// Err is the underlying error, which may be internal and must not be shown to the user.
+	Err error `json:"-"`
+}
+
+// RediscoverEndpoint saves the newly discovered catalog of the endpoint and applies the schema changes
+// to the syncs which read from it. The catalog is saved even if some of the syncs cannot be updated.
+// Those syncs are returned and the rest are updated regardless.
+func (a *App) RediscoverEndpoint(ctx context.Context, id int) ([]*SyncFailure, error) {
+	// Fetch the current endpoint object from the database.
+	endpoint, err := a.FindEndpointByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := a.ConnectorConfig(ctx, &endpoint.Config)
+	if err != nil {
+		return nil, err
+	}
 
 	msg, err := a.Discover(ctx, endpoint.Connector, config)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	endpoint.Catalog = *msg
 
 	endpoint.LastDiscovered = time.Now()
 
-	if err := a.DBService.UpdateEndpoint(ctx, id, endpoint); err != nil {
-		return err
+	// The changes to the catalog itself are not recorded. The changes it causes to syncs are.
+	if err := a.DBService.UpdateEndpoint(NewAuditContext(ctx, AuditActionRediscover, AuditEntityEndpoint, nil, nil), id, endpoint); err != nil {
+		return nil, err
 	}
 
-	return nil
+	// Let all the syncs reading from this endpoint know about the changes to the catalog.
+	syncs, _, err := a.FindSyncs(ctx, SyncFilter{SourceEndpointID: &id})
+	if err != nil {
+		return nil, err
+	}
+	failures := []*SyncFailure{}
+	for _, sync := range syncs {
+		if err := a.applySchemaChanges(ctx, sync, &endpoint.Catalog); err != nil {
+			failures = append(failures, &SyncFailure{
+				SyncID:   sync.ID,
+				SyncName: sync.Name,
+				Error:    ErrorMessage(err),
+				Err:      err,
+			})
+		}
+	}
+
+	return failures, nil
+}
+
+// applySchemaChanges records the differences between the newly discovered catalog and the
+// configured catalog of the sync and acts on them according to the sync's schema change policy.
+func (a *App) applySchemaChanges(ctx context.Context, sync *Sync, catalog *Message) error {
+	changes := sync.DiffCatalog(catalog.Catalog)
+	breaking := HasBreakingSchemaChanges(changes)
+
+	upd := &SyncUpdate{SchemaChanges: &changes}
+
+	switch sync.SchemaChangePolicy {
+	case SchemaChangePolicyPropagate:
+		if len(changes) == 0 || breaking {
+			break
+		}
+
+		// Rebuild the catalog form from the new catalog while retaining the user's selections.
+		// Newly discovered streams are not replicated until the user selects them.
+		config := a.MessageToForm(
+			ctx,
+			catalog,
+			sync.DestinationEndpoint.Connector.Spec.Spec.SupportedDestinationSyncModes,
+		)
+		config.Merge(&sync.Config)
+		for _, field := range config.Catalog {
+			if !sync.Config.hasStream(field.Stream.Key()) {
+				field.IsStreamSelected = false
+			}
+		}
+		upd = &SyncUpdate{Config: config}
+
+	case SchemaChangePolicyPause:
+		if breaking {
+			enabled := false
+			upd.Enabled = &enabled
+		}
+	}
+
+	_, err := a.UpdateSync(ctx, sync.ID, upd)
+	return err
 }
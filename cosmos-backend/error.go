@@ -7,11 +7,15 @@ import (
 
 // Application error codes.
 const (
-	EINVALID        = "invalid"
-	EINTERNAL       = "internal"
-	ECONFLICT       = "conflict"
-	ENOTFOUND       = "not_found"
-	ENOTIMPLEMENTED = "not_implemented"
+	EINVALID         = "invalid"
+	EINTERNAL        = "internal"
+	ECONFLICT        = "conflict"
+	ENOTFOUND        = "not_found"
+	ENOTIMPLEMENTED  = "not_implemented"
+	EPURGED          = "purged"
+	EUNAUTHORIZED    = "unauthorized"
+	EFORBIDDEN       = "forbidden"
+	ETOOMANYREQUESTS = "too_many_requests"
 )
 
 // Error represents an application-specific error.
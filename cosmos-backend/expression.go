@@ -0,0 +1,221 @@
+package cosmos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/iancoleman/orderedmap"
+)
+
+// ErrNoOutput is returned by Expression.Evaluate if the expression doesn't produce any value,
+// e.g, select(.status != "deleted") for a deleted record.
+var ErrNoOutput = errors.New("expression produced no output")
+
+// Expression is a compiled filter or projection expression which is evaluated against the data of a record.
+type Expression interface {
+	Evaluate(data map[string]interface{}) (interface{}, error)
+}
+
+type ExpressionService interface {
+	CompileExpression(ctx context.Context, expr string) (Expression, error)
+
+	// ProjectedFields returns the fields of the records produced by a projection expression. It returns
+	// an error if the projection doesn't build an object with fixed keys, since the fields of the records
+	// produced by it cannot be derived otherwise.
+	ProjectedFields(ctx context.Context, expr string) ([]ProjectedField, error)
+}
+
+// ProjectedField is a field of the records produced by a projection. Its value is the value of the field
+// at Path in the record data, or is computed by an expression (e.g, .cents / 100) if Path is nil.
+type ProjectedField struct {
+	Name string
+	Path []string
+}
+
+// StreamExpressions holds the compiled filter and projection expressions of a single stream.
+// A nil expression is never evaluated.
+type StreamExpressions struct {
+	Filter     Expression
+	Projection Expression
+
+	// Fields are the fields of the records produced by the projection.
+	Fields []ProjectedField
+}
+
+// Keep returns true if the record data satisfies the filter expression. Like jq, every value
+// other than false and null satisfies the filter. Records for which the filter doesn't produce
+// any value are dropped, so that filters can be written as select(.status != "deleted").
+func (e *StreamExpressions) Keep(data map[string]interface{}) (bool, error) {
+	if e.Filter == nil {
+		return true, nil
+	}
+	v, err := e.Filter.Evaluate(data)
+	if errors.Is(err, ErrNoOutput) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return v != nil && v != false, nil
+}
+
+// Project returns the record data transformed by the projection expression.
+func (e *StreamExpressions) Project(data map[string]interface{}) (map[string]interface{}, error) {
+	if e.Projection == nil {
+		return data, nil
+	}
+	v, err := e.Projection.Evaluate(data)
+	if err != nil {
+		return nil, err
+	}
+	projected, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("projection must produce an object, got %T", v)
+	}
+	return projected, nil
+}
+
+// ProjectSchema returns the JSON schema of the records produced by the projection given the JSON schema
+// of the records that it is applied to. It returns an error if the projection references a field which
+// is not in the schema, e.g, a field which is not selected or is dropped by a field policy.
+func (e *StreamExpressions) ProjectSchema(schema orderedmap.OrderedMap) (orderedmap.OrderedMap, error) {
+	if e.Projection == nil {
+		return schema, nil
+	}
+
+	projected := orderedmap.New()
+	for _, key := range schema.Keys() {
+		if key != "properties" && key != "required" {
+			v, _ := schema.Get(key)
+			projected.Set(key, v)
+		}
+	}
+
+	required := map[string]bool{}
+	if v, ok := schema.Get("required"); ok {
+		if r, ok := v.([]interface{}); ok {
+			for _, name := range r {
+				if name, ok := name.(string); ok {
+					required[name] = true
+				}
+			}
+		}
+	}
+
+	properties := orderedmap.New()
+	projectedRequired := []interface{}{}
+	for _, field := range e.Fields {
+		// The type of computed values isn't known, so they can have any value.
+		if field.Path == nil {
+			properties.Set(field.Name, *orderedmap.New())
+			continue
+		}
+		property, err := schemaProperty(schema, field.Path)
+		if err != nil {
+			return orderedmap.OrderedMap{}, err
+		}
+		properties.Set(field.Name, property)
+		if len(field.Path) == 1 && required[field.Path[0]] {
+			projectedRequired = append(projectedRequired, field.Name)
+		}
+	}
+	projected.Set("properties", *properties)
+	if len(projectedRequired) != 0 {
+		projected.Set("required", projectedRequired)
+	}
+
+	return *projected, nil
+}
+
+// preserves returns true if the projection keeps the top-level field of path under the same name.
+func (e *StreamExpressions) preserves(path []string) bool {
+	if e.Projection == nil || len(path) == 0 {
+		return true
+	}
+	for _, field := range e.Fields {
+		if field.Name == path[0] && len(field.Path) == 1 && field.Path[0] == path[0] {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaProperty returns the schema of the property at the given path in a JSON schema.
+// Properties of objects whose properties are not described by the schema can have any value.
+func schemaProperty(schema orderedmap.OrderedMap, path []string) (orderedmap.OrderedMap, error) {
+	for i, key := range path {
+		v, ok := schema.Get("properties")
+		if !ok {
+			return *orderedmap.New(), nil
+		}
+		properties, ok := v.(orderedmap.OrderedMap)
+		if !ok {
+			return *orderedmap.New(), nil
+		}
+		v, ok = properties.Get(key)
+		if !ok {
+			return orderedmap.OrderedMap{}, fmt.Errorf("projection references %s which is not a field of the stream", strings.Join(path[:i+1], "."))
+		}
+		if schema, ok = v.(orderedmap.OrderedMap); !ok {
+			return *orderedmap.New(), nil
+		}
+	}
+	return schema, nil
+}
+
+// CompileStreamExpressions compiles the filter and projection expressions of all the selected
+// streams in the sync. Streams without any expressions are omitted from the result.
+func (a *App) CompileStreamExpressions(ctx context.Context, sync *Sync) (map[string]*StreamExpressions, error) {
+	result := map[string]*StreamExpressions{}
+
+	for _, field := range sync.Config.Catalog {
+		if !field.IsStreamSelected || (field.Filter == "" && field.Projection == "") {
+			continue
+		}
+
+		expressions := &StreamExpressions{}
+		if field.Filter != "" {
+			expr, err := a.CompileExpression(ctx, field.Filter)
+			if err != nil {
+				return nil, Errorf(EINVALID, "Stream %s: invalid filter expression. %s", field.StreamName, ErrorMessage(err))
+			}
+			expressions.Filter = expr
+		}
+		if field.Projection != "" {
+			expr, err := a.CompileExpression(ctx, field.Projection)
+			if err != nil {
+				return nil, Errorf(EINVALID, "Stream %s: invalid projection expression. %s", field.StreamName, ErrorMessage(err))
+			}
+			expressions.Projection = expr
+
+			if expressions.Fields, err = a.ProjectedFields(ctx, field.Projection); err != nil {
+				return nil, Errorf(EINVALID, "Stream %s: invalid projection expression. %s", field.StreamName, ErrorMessage(err))
+			}
+
+			// Field policies are applied before the projection.
+			stream := field.PrunedStream()
+			for _, policy := range field.FieldPolicies {
+				policy.ApplyToSchema(&stream.JSONSchema)
+			}
+			if _, err := expressions.ProjectSchema(stream.JSONSchema); err != nil {
+				return nil, Errorf(EINVALID, "Stream %s: %s", field.StreamName, err)
+			}
+
+			// The destination deduplicates records and tracks the cursor by these fields.
+			if field.usesCursorField() && !expressions.preserves(field.SelectedCursorField) {
+				return nil, Errorf(EINVALID, "Stream %s: the projection must keep the cursor field", field.StreamName)
+			}
+			if field.usesPrimaryKey() {
+				for _, key := range field.SelectedPrimaryKey {
+					if !expressions.preserves(key) {
+						return nil, Errorf(EINVALID, "Stream %s: the projection must keep the primary key", field.StreamName)
+					}
+				}
+			}
+		}
+		result[field.Stream.Key()] = expressions
+	}
+
+	return result, nil
+}
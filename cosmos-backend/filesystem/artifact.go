@@ -1,13 +1,14 @@
 package filesystem
 
 import (
+	"bytes"
 	"cosmos"
-	"fmt"
+	"io"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -17,50 +18,62 @@ import (
 
 var json = jsoniter.ConfigDefault
 
+// Decrypted copies of the artifacts that are mounted into connector containers
+// are written to this directory of the artifactory.
+const mountDir = ".mount"
+
 var _ cosmos.ArtifactService = (*ArtifactService)(nil)
 
 type ArtifactService struct {
+	// Dir is the directory in which the artifacts are stored.
+	Dir string
+
+	// HostDir is Dir as seen by the docker daemon. For Docker-in-Docker, this is the path on the host.
+	HostDir string
+
+	// Cipher encrypts the artifacts at rest. Artifacts are not encrypted if it is nil.
+	Cipher *cosmos.Cipher
 }
 
 func NewArtifactService() *ArtifactService {
-	return &ArtifactService{}
+	return &ArtifactService{
+		Dir: cosmos.ArtifactDir,
+	}
 }
 
 func (s *ArtifactService) GetArtifactory(syncID int, executionDate time.Time) (*cosmos.Artifactory, error) {
-	path := filepath.Join(
-		cosmos.ArtifactDir,
-		strconv.Itoa(syncID),
-		executionDate.Format(time.RFC3339),
-	)
+	artifactory := s.LookupArtifactory(syncID, executionDate)
 
-	if err := os.MkdirAll(path, 0777); err != nil {
+	if err := os.MkdirAll(artifactory.Path, 0777); err != nil {
 		return nil, err
 	}
 
-	return &cosmos.Artifactory{Path: path}, nil
+	return artifactory, nil
 }
 
-func (s *ArtifactService) GetArtifactRef(artifactory *cosmos.Artifactory, id int, attempt int32) (*log.Logger, error) {
-	var err error
+func (s *ArtifactService) LookupArtifactory(syncID int, executionDate time.Time) *cosmos.Artifactory {
+	path := filepath.Join(
+		s.Dir,
+		strconv.Itoa(syncID),
+		executionDate.Format(time.RFC3339),
+	)
 
-	artifactory.Once[id].Do(func() {
-		var file *os.File
-		file, err = os.OpenFile(filepath.Join(artifactory.Path, cosmos.ArtifactNames[id]), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	return &cosmos.Artifactory{Path: path}
+}
+
+func (s *ArtifactService) GetArtifactRef(artifactory *cosmos.Artifactory, id int, attempt int32) (*cosmos.ArtifactLogger, error) {
+	return artifactory.Logger(id, attempt, func() (*cosmos.ArtifactLogger, error) {
+		file, err := os.OpenFile(filepath.Join(artifactory.Path, cosmos.ArtifactFileName(id, attempt)), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+		if err != nil {
+			return nil, err
+		}
+		w, err := s.Cipher.NewWriter(file)
 		if err != nil {
-			return
+			file.Close()
+			return nil, err
 		}
-		artifactory.Artifacts[id] = log.New(file, fmt.Sprintf("[Attempt %3d] ", attempt), log.LstdFlags)
+		return cosmos.NewArtifactLogger(w, id, attempt), nil
 	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	if artifactory.Artifacts[id] == nil {
-		return nil, fmt.Errorf("artifact for %s is unavailable", cosmos.ArtifactNames[id])
-	}
-
-	return artifactory.Artifacts[id], nil
 }
 
 func (s *ArtifactService) WriteArtifact(artifactory *cosmos.Artifactory, id int, contents interface{}) error {
@@ -79,6 +92,10 @@ func (s *ArtifactService) WriteArtifact(artifactory *cosmos.Artifactory, id int,
 		return err
 	}
 
+	if b, err = s.Cipher.Seal(b); err != nil {
+		return err
+	}
+
 	_, err = file.Write(b)
 	if err != nil {
 		return err
@@ -93,30 +110,248 @@ func (s *ArtifactService) GetArtifactPath(artifactory *cosmos.Artifactory, id in
 		return nil
 	}
 
+	// Connectors can't read encrypted artifacts. Mount a decrypted copy instead,
+	// which is removed when the artifactory is closed.
+	if s.Cipher != nil {
+		data, err := s.GetArtifactData(artifactory, id)
+		if err != nil {
+			return nil
+		}
+		path = filepath.Join(artifactory.Path, mountDir, cosmos.ArtifactNames[id])
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil
+		}
+		if err := ioutil.WriteFile(path, data, 0600); err != nil {
+			return nil
+		}
+	}
+
 	// For Docker-in-Docker, we have to return the path as it would be on the host.
-	path = strings.TrimPrefix(path, cosmos.ArtifactDir)
-	path = filepath.Join(os.Getenv("ARTIFACT_DIR"), path)
+	path = strings.TrimPrefix(path, s.Dir)
+	path = filepath.Join(s.HostDir, path)
 
 	return &path
 }
 
 func (s *ArtifactService) GetArtifactData(artifactory *cosmos.Artifactory, id int) ([]byte, error) {
-	path := filepath.Join(artifactory.Path, cosmos.ArtifactNames[id])
+	files, err := s.listFiles(artifactory, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, cosmos.Errorf(cosmos.ENOTFOUND, "Requested artifact does not exist")
+	}
 
-	if _, err := os.Stat(path); err != nil {
-		if os.IsNotExist(err) {
-			return nil, cosmos.Errorf(cosmos.ENOTFOUND, "Requested artifact does not exist")
+	var data []byte
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f.path)
+		if err != nil {
+			return nil, err
 		}
+		data = append(data, b...)
+	}
+
+	return s.Cipher.OpenArtifact(id, data)
+}
+
+func (s *ArtifactService) GetArtifactAttemptData(artifactory *cosmos.Artifactory, id int, attempt int32) ([]byte, error) {
+	data, err := ioutil.ReadFile(filepath.Join(artifactory.Path, cosmos.ArtifactFileName(id, attempt)))
+	if os.IsNotExist(err) {
+		return nil, cosmos.Errorf(cosmos.ENOTFOUND, "Requested artifact does not exist for attempt %d", attempt)
+	} else if err != nil {
 		return nil, err
 	}
+	return s.Cipher.OpenLines(data)
+}
+
+// GetArtifactDataFrom returns the complete lines of a log artifact starting at the given offset
+// along with the offset just past them. The attempts of a log artifact are read one after the other
+// as if they were a single file. Offsets refer to the stored (possibly encrypted) artifact.
+func (s *ArtifactService) GetArtifactDataFrom(artifactory *cosmos.Artifactory, id int, offset int64) ([]byte, int64, error) {
+	files, err := s.listFiles(artifactory, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(files) == 0 {
+		return nil, 0, cosmos.Errorf(cosmos.ENOTFOUND, "Requested artifact does not exist")
+	}
+
+	var data []byte
+	start := offset
+	for _, f := range files {
+		if start >= f.Size {
+			start -= f.Size
+			continue
+		}
+		b, err := readFrom(f.path, start)
+		if err != nil {
+			return nil, 0, err
+		}
+		data = append(data, b...)
+		start = 0
+	}
+
+	data = data[:bytes.LastIndexByte(data, '\n')+1]
+	next := offset + int64(len(data))
+
+	data, err = s.Cipher.OpenLines(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return data, next, nil
+}
+
+func (s *ArtifactService) ListArtifacts(artifactory *cosmos.Artifactory) ([]*cosmos.ArtifactFile, error) {
+	artifacts := []*cosmos.ArtifactFile{}
+
+	for id := 0; id < cosmos.ArtifactMax; id++ {
+		files, err := s.listFiles(artifactory, id)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			artifacts = append(artifacts, &f.ArtifactFile)
+		}
+	}
 
-	return ioutil.ReadFile(path)
+	return artifacts, nil
 }
 
 func (s *ArtifactService) CloseArtifactory(artifactory *cosmos.Artifactory) {
-	for _, artifact := range artifactory.Artifacts {
-		if artifact != nil {
-			artifact.Writer().(*os.File).Close()
+	for _, artifact := range artifactory.Loggers() {
+		artifact.Close()
+	}
+
+	os.RemoveAll(filepath.Join(artifactory.Path, mountDir))
+}
+
+func (s *ArtifactService) GetArtifactorySize(artifactory *cosmos.Artifactory) (int64, error) {
+	var size int64
+
+	err := filepath.Walk(artifactory.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+
+	return size, err
+}
+
+func (s *ArtifactService) DeleteArtifactory(artifactory *cosmos.Artifactory) error {
+	return os.RemoveAll(artifactory.Path)
+}
+
+type artifactFile struct {
+	cosmos.ArtifactFile
+	path string
+}
+
+// listFiles returns the files of an artifact. The files of a log artifact are ordered by attempt.
+func (s *ArtifactService) listFiles(artifactory *cosmos.Artifactory, id int) ([]*artifactFile, error) {
+	var paths []string
+	if cosmos.IsLogArtifact(id) {
+		matches, err := filepath.Glob(filepath.Join(artifactory.Path, cosmos.ArtifactNames[id]+".*"))
+		if err != nil {
+			return nil, err
 		}
+		paths = matches
+		// Older runs have a single file without the attempt suffix.
+		if len(paths) == 0 {
+			paths = []string{filepath.Join(artifactory.Path, cosmos.ArtifactNames[id])}
+		}
+	} else {
+		paths = []string{filepath.Join(artifactory.Path, cosmos.ArtifactNames[id])}
 	}
+
+	files := []*artifactFile{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		end := info.ModTime()
+		f := &artifactFile{
+			ArtifactFile: cosmos.ArtifactFile{
+				ID:    id,
+				Name:  cosmos.ArtifactNames[id],
+				Size:  info.Size(),
+				Start: &end,
+				End:   &end,
+			},
+			path: path,
+		}
+
+		if cosmos.IsLogArtifact(id) {
+			if base := filepath.Base(path); base != cosmos.ArtifactNames[id] {
+				attempt, err := strconv.ParseInt(strings.TrimPrefix(base, cosmos.ArtifactNames[id]+"."), 10, 32)
+				if err != nil {
+					continue
+				}
+				f.Attempt = int32(attempt)
+			}
+			if start, ok := s.firstLineTime(path); ok {
+				f.Start = &start
+				// Modification times are not as precise as the timestamps of the lines.
+				if start.After(end) {
+					f.End = &start
+				}
+			}
+		}
+
+		files = append(files, f)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Attempt < files[j].Attempt
+	})
+
+	return files, nil
+}
+
+// firstLineTime returns the time at which the first line of a log artifact was written.
+func (s *ArtifactService) firstLineTime(path string) (time.Time, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer file.Close()
+
+	// Encrypted lines have to be read in full to be decrypted.
+	b := make([]byte, 4096)
+	n, _ := io.ReadFull(file, b)
+	if i := bytes.IndexByte(b[:n], '\n'); i >= 0 {
+		n = i + 1
+	}
+
+	line, err := s.Cipher.OpenLines(b[:n])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return cosmos.ParseArtifactLogTime(line)
+}
+
+func readFrom(path string, offset int64) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(file)
 }
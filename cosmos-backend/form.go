@@ -1,7 +1,11 @@
 package cosmos
 
 import (
+	"fmt"
 	"regexp"
+	"strings"
+
+	"github.com/iancoleman/orderedmap"
 )
 
 const (
@@ -37,19 +41,27 @@ type FormFieldSpec struct {
 	DependsOnValue []interface{} `json:"dependsOnValue"`
 	OneOfKey       bool          `json:"oneOfKey"`
 	Ignore         bool          `json:"ignore"`
+
+	// IsSet is true if a secret field has a value. Values of secret fields are never returned by the API.
+	IsSet bool `json:"isSet,omitempty"`
 }
 
 type FormFieldCatalog struct {
-	Stream              Stream     `json:"stream"`
-	StreamNamespace     *string    `json:"streamNamespace"`
-	StreamName          string     `json:"streamName"`
-	IsStreamSelected    bool       `json:"isStreamSelected"`
-	SyncModes           [][]string `json:"syncModes"`
-	SelectedSyncMode    []string   `json:"selectedSyncMode"`
-	CursorFields        [][]string `json:"cursorFields"`
-	SelectedCursorField []string   `json:"selectedCursorField"`
-	PrimaryKeys         [][]string `json:"primaryKeys"`
-	SelectedPrimaryKey  [][]string `json:"selectedPrimaryKey"`
+	Stream              Stream         `json:"stream"`
+	StreamNamespace     *string        `json:"streamNamespace"`
+	StreamName          string         `json:"streamName"`
+	IsStreamSelected    bool           `json:"isStreamSelected"`
+	SyncModes           [][]string     `json:"syncModes"`
+	SelectedSyncMode    []string       `json:"selectedSyncMode"`
+	CursorFields        [][]string     `json:"cursorFields"`
+	SelectedCursorField []string       `json:"selectedCursorField"`
+	PrimaryKeys         [][]string     `json:"primaryKeys"`
+	SelectedPrimaryKey  [][]string     `json:"selectedPrimaryKey"`
+	Fields              []string       `json:"fields"`
+	SelectedFields      []string       `json:"selectedFields"`
+	FieldPolicies       []*FieldPolicy `json:"fieldPolicies"`
+	Filter              string         `json:"filter"`
+	Projection          string         `json:"projection"`
 }
 
 func (f *FormFieldSpec) EnumContainsValue(value interface{}) bool {
@@ -104,7 +116,70 @@ func (f *FormFieldCatalog) IsPrimaryKeyAvailable(primaryKey [][]string) bool {
 	return true
 }
 
-func (f *Form) ToSpec() map[string]interface{} {
+// hasStream returns true if the catalog form contains the given stream.
+func (f *Form) hasStream(key string) bool {
+	for _, field := range f.Catalog {
+		if field.Stream.Key() == key {
+			return true
+		}
+	}
+	return false
+}
+
+// ExcludedFields returns the top-level fields of the stream which have not been selected.
+func (f *FormFieldCatalog) ExcludedFields() []string {
+	// All fields are selected unless the user explicitly made a selection.
+	if f.SelectedFields == nil {
+		return nil
+	}
+
+	excluded := []string{}
+	for _, field := range f.Fields {
+		if !contains(f.SelectedFields, field) {
+			excluded = append(excluded, field)
+		}
+	}
+	return excluded
+}
+
+// PrunedStream returns a copy of the stream whose JSON schema only contains the selected fields.
+func (f *FormFieldCatalog) PrunedStream() Stream {
+	excluded := f.ExcludedFields()
+	if len(excluded) == 0 {
+		return f.Stream
+	}
+
+	stream := Stream{}
+	b, _ := json.Marshal(f.Stream)
+	json.Unmarshal(b, &stream)
+
+	for _, field := range excluded {
+		updateSchemaProperty(&stream.JSONSchema, []string{field}, func(orderedmap.OrderedMap) *orderedmap.OrderedMap {
+			return nil
+		})
+	}
+
+	return stream
+}
+
+// usesCursorField returns true if the selected sync mode requires the selected cursor field.
+func (f *FormFieldCatalog) usesCursorField() bool {
+	return len(f.SelectedSyncMode) == 2 &&
+		f.SelectedSyncMode[0] == SyncModeIncremental &&
+		len(f.SelectedCursorField) != 0
+}
+
+// usesPrimaryKey returns true if the selected sync mode requires the selected primary key.
+func (f *FormFieldCatalog) usesPrimaryKey() bool {
+	return len(f.SelectedSyncMode) == 2 &&
+		(f.SelectedSyncMode[1] == DestinationSyncModeAppendDedup ||
+			f.SelectedSyncMode[1] == DestinationSyncModeUpsertDedup) &&
+		len(f.SelectedPrimaryKey) != 0
+}
+
+// ToSpec builds the connector config from the values of the form.
+// The values of secret fields which have been encrypted at rest are decrypted with c.
+func (f *Form) ToSpec(c *Cipher) (map[string]interface{}, error) {
 	result := map[string]interface{}{}
 
 	for _, field := range f.Spec {
@@ -128,10 +203,18 @@ func (f *Form) ToSpec() map[string]interface{} {
 			}
 			m = m[p].(map[string]interface{})
 		}
-		m[field.Path[len(field.Path)-1]] = field.Value
+
+		value := field.Value
+		if field.Secret {
+			var err error
+			if value, err = c.OpenValue(value); err != nil {
+				return nil, fmt.Errorf("unable to decrypt %s. err: %w", strings.Join(field.Path, "."), err)
+			}
+		}
+		m[field.Path[len(field.Path)-1]] = value
 	}
 
-	return result
+	return result, nil
 }
 
 func (f *Form) ToConfiguredCatalog() map[string]interface{} {
@@ -151,18 +234,15 @@ func (f *Form) ToConfiguredCatalog() map[string]interface{} {
 
 		m := map[string]interface{}{}
 
-		m["stream"] = field.Stream
+		m["stream"] = field.PrunedStream()
 
 		m["sync_mode"] = field.SelectedSyncMode[0]
-		if field.SelectedSyncMode[0] == SyncModeIncremental &&
-			len(field.SelectedCursorField) != 0 {
+		if field.usesCursorField() {
 			m["cursor_field"] = field.SelectedCursorField
 		}
 
 		m["destination_sync_mode"] = field.SelectedSyncMode[1]
-		if (field.SelectedSyncMode[1] == DestinationSyncModeAppendDedup ||
-			field.SelectedSyncMode[1] == DestinationSyncModeUpsertDedup) &&
-			len(field.SelectedPrimaryKey) != 0 {
+		if field.usesPrimaryKey() {
 			m["primary_key"] = field.SelectedPrimaryKey
 		}
 
@@ -233,6 +313,21 @@ func (f *Form) Merge(patch *Form) {
 					baseField.SelectedSyncMode = patchField.SelectedSyncMode
 					baseField.SelectedCursorField = patchField.SelectedCursorField
 					baseField.SelectedPrimaryKey = patchField.SelectedPrimaryKey
+					baseField.FieldPolicies = patchField.FieldPolicies
+					baseField.Filter = patchField.Filter
+					baseField.Projection = patchField.Projection
+
+					// Fields which were explicitly excluded remain excluded. Fields which
+					// have been newly discovered since are selected.
+					if patchField.SelectedFields != nil {
+						excluded := patchField.ExcludedFields()
+						baseField.SelectedFields = []string{}
+						for _, field := range baseField.Fields {
+							if !contains(excluded, field) {
+								baseField.SelectedFields = append(baseField.SelectedFields, field)
+							}
+						}
+					}
 					break
 				}
 			}
@@ -243,6 +338,15 @@ func (f *Form) Merge(patch *Form) {
 	}
 }
 
+func contains(arr []string, element string) bool {
+	for _, item := range arr {
+		if item == element {
+			return true
+		}
+	}
+	return false
+}
+
 func testEq(a, b []string) bool {
 	if (a == nil) != (b == nil) {
 		return false
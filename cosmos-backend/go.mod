@@ -3,13 +3,23 @@ module cosmos
 go 1.16
 
 require (
+	github.com/BurntSushi/toml v0.4.1
 	github.com/gorilla/handlers v1.5.1
 	github.com/gorilla/mux v1.8.0
 	github.com/iancoleman/orderedmap v0.2.0
+	github.com/itchyny/gojq v0.12.5
 	github.com/jackc/pgx/v4 v4.11.0
 	github.com/json-iterator/go v1.1.11
+	github.com/minio/minio-go/v7 v7.0.12
 	github.com/mitchellh/mapstructure v1.4.1
+	github.com/prometheus/client_golang v1.11.0
 	github.com/xeipuuv/gojsonschema v1.2.0
+	go.opentelemetry.io/otel v1.0.0
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc v1.0.0
+	go.opentelemetry.io/otel/exporters/stdout/stdouttrace v1.0.0
+	go.opentelemetry.io/otel/sdk v1.0.0
+	go.opentelemetry.io/otel/trace v1.0.0
 	go.temporal.io/sdk v1.8.0
 	go.uber.org/zap v1.13.0
+	golang.org/x/crypto v0.0.0-20210322153248-0c34fe9e7dc2
 )
@@ -1,18 +1,28 @@
 package http
 
 import (
+	"bytes"
 	"cosmos"
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 )
 
+// tailInterval is how often a tailed artifact is checked for new lines.
+const tailInterval = time.Second
+
 func (s *Server) registerArtifactRoutes(r *mux.Router) {
-	r.HandleFunc("/artifacts/{runID}/{artifactID}", s.getArtifact).Methods("GET")
+	r.HandleFunc("/artifacts/{runID}/{artifactID}", s.authorize(cosmos.RoleViewer, s.runScope("runID"), s.getArtifact)).Methods("GET")
+	r.HandleFunc("/artifacts/{runID}/{artifactID}/tail", s.authorize(cosmos.RoleViewer, s.runScope("runID"), s.tailArtifact)).Methods("GET")
 }
 
+// getArtifact returns the contents of an artifact. The attempts of a log artifact are returned
+// one after the other unless the "attempt" query parameter is given. The "level" query parameter
+// restricts the lines of a log artifact to those at least as severe as the given log level.
+// Log artifacts are rendered as text unless the "format" query parameter is "json".
 func (s *Server) getArtifact(w http.ResponseWriter, r *http.Request) {
 	runID, err := strconv.Atoi(mux.Vars(r)["runID"])
 	if err != nil {
@@ -31,17 +41,188 @@ func (s *Server) getArtifact(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	artifactory, err := s.App.GetArtifactory(run.SyncID, run.ExecutionDate)
+	artifactory, err := s.App.GetRunArtifactory(run)
 	if err != nil {
 		s.ReplyWithSanitizedError(w, r, err)
 		return
 	}
 
-	data, err := s.App.GetArtifactData(artifactory, artifactID)
+	var data []byte
+	if v := r.URL.Query().Get("attempt"); v != "" {
+		attempt, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || !cosmos.IsLogArtifact(artifactID) {
+			s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid attempt"))
+			return
+		}
+		data, err = s.App.GetArtifactAttemptData(artifactory, artifactID, int32(attempt))
+		if err != nil {
+			s.ReplyWithSanitizedError(w, r, err)
+			return
+		}
+	} else {
+		data, err = s.App.GetArtifactData(artifactory, artifactID)
+		if err != nil {
+			s.ReplyWithSanitizedError(w, r, err)
+			return
+		}
+	}
+
+	contentType := "text/plain; charset=utf-8"
+	if cosmos.IsLogArtifact(artifactID) {
+		format, err := logFormat(r)
+		if err != nil {
+			s.ReplyWithSanitizedError(w, r, err)
+			return
+		}
+		if level := r.URL.Query().Get("level"); level != "" {
+			if !cosmos.IsValidLogLevel(level) {
+				s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid log level"))
+				return
+			}
+			data = cosmos.FilterLogLines(data, level)
+		}
+		data = cosmos.FormatLogLines(data, format)
+		if format == cosmos.LogFormatJSON {
+			contentType = "application/x-ndjson"
+		}
+	}
+
+	// ServeContent takes care of HTTP Range requests.
+	w.Header().Set("Content-Type", contentType)
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+}
+
+// tailArtifact streams the lines of a log artifact as server-sent events as they are appended.
+//
+// Lines are sent in batches. The ID of the last event of each batch is the offset in the stored
+// artifact just past the lines sent so far. Streaming resumes from the offset in the "offset" query
+// parameter or the "Last-Event-ID" header (sent by the browser on reconnect). Lines are rendered
+// as text unless the "format" query parameter is "json". An "end" event is sent and the stream
+// is closed once the run reaches a terminal state and all of its lines have been sent.
+func (s *Server) tailArtifact(w http.ResponseWriter, r *http.Request) {
+	runID, err := strconv.Atoi(mux.Vars(r)["runID"])
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid run ID"))
+		return
+	}
+	artifactID, err := strconv.Atoi(mux.Vars(r)["artifactID"])
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid artifact ID"))
+		return
+	}
+
+	switch artifactID {
+	case cosmos.ArtifactSource, cosmos.ArtifactDestination, cosmos.ArtifactNormalization, cosmos.ArtifactWorker:
+	default:
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Only log artifacts can be tailed"))
+		return
+	}
+
+	offset := int64(0)
+	for _, v := range []string{r.Header.Get("Last-Event-ID"), r.URL.Query().Get("offset")} {
+		if v == "" {
+			continue
+		}
+		if offset, err = strconv.ParseInt(v, 10, 64); err != nil || offset < 0 {
+			s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid offset"))
+			return
+		}
+		break
+	}
+
+	format, err := logFormat(r)
 	if err != nil {
 		s.ReplyWithSanitizedError(w, r, err)
 		return
 	}
 
-	fmt.Fprint(w, string(data))
+	run, err := s.App.FindRunByID(r.Context(), runID)
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	artifactory, err := s.App.GetRunArtifactory(run)
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.ENOTIMPLEMENTED, "Streaming is not supported"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		// The run's state must be checked before reading the artifact so that
+		// no lines are missed if the run finishes in between.
+		done := run.IsTerminalState()
+
+		// Only complete lines are returned so that encrypted lines can be decrypted.
+		data, next, err := s.App.GetArtifactDataFrom(artifactory, artifactID, offset)
+		if err != nil {
+			if cosmos.ErrorCode(err) != cosmos.ENOTFOUND {
+				s.LogError(r, err)
+				return
+			}
+			next = offset
+		}
+
+		lines := bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+		for i, line := range lines {
+			if len(line) == 0 {
+				continue
+			}
+			// Offsets of individual lines aren't known once they have been decrypted.
+			// So, only the last event of the batch carries the offset.
+			if i == len(lines)-1 {
+				fmt.Fprintf(w, "id: %d\n", next)
+			}
+			// Messages may span multiple lines, each of which must be sent in its own data field.
+			for _, l := range bytes.Split(bytes.TrimRight(cosmos.FormatLogLines(line, format), "\r\n"), []byte("\n")) {
+				fmt.Fprintf(w, "data: %s\n", l)
+			}
+			fmt.Fprint(w, "\n")
+		}
+		offset = next
+
+		if done {
+			fmt.Fprintf(w, "event: end\ndata: %s\n\n", run.Status)
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			return
+		case <-time.After(tailInterval):
+		}
+
+		if run, err = s.App.FindRunByID(r.Context(), runID); err != nil {
+			s.LogError(r, err)
+			return
+		}
+	}
+}
+
+// logFormat returns the output format of log artifacts requested in the "format" query parameter.
+func logFormat(r *http.Request) (string, error) {
+	switch format := r.URL.Query().Get("format"); format {
+	case "":
+		return cosmos.LogFormatText, nil
+	case cosmos.LogFormatText, cosmos.LogFormatJSON:
+		return format, nil
+	default:
+		return "", cosmos.Errorf(cosmos.EINVALID, "Invalid log format: %s", format)
+	}
 }
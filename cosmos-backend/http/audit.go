@@ -0,0 +1,73 @@
+package http
+
+import (
+	"cosmos"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+func (s *Server) registerAuditRoutes(r *mux.Router) {
+	r.HandleFunc("/audit", s.authorize(cosmos.RoleAdmin, nil, s.findAuditEntries)).Methods("GET")
+}
+
+// findAuditEntries returns the audit log, most recent entries first. It can be filtered with the
+// "userID", "action", "entityType" and "entityID" query params and paged with "offset" and "limit".
+func (s *Server) findAuditEntries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := cosmos.AuditFilter{}
+
+	if v := query.Get("userID"); v != "" {
+		userID, err := strconv.Atoi(v)
+		if err != nil {
+			s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid user ID"))
+			return
+		}
+		filter.UserID = &userID
+	}
+	if v := query.Get("action"); v != "" {
+		filter.Action = &v
+	}
+	if v := query.Get("entityType"); v != "" {
+		filter.EntityType = &v
+	}
+	if v := query.Get("entityID"); v != "" {
+		entityID, err := strconv.Atoi(v)
+		if err != nil {
+			s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid entity ID"))
+			return
+		}
+		filter.EntityID = &entityID
+	}
+
+	var err error
+	if v := query.Get("offset"); v != "" {
+		if filter.Offset, err = strconv.Atoi(v); err != nil {
+			s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid offset"))
+			return
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid limit"))
+			return
+		}
+	}
+
+	entries, totalEntries, err := s.App.FindAuditEntries(r.Context(), filter)
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	ret := map[string]interface{}{
+		"entries":      entries,
+		"totalEntries": totalEntries,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&ret); err != nil {
+		s.LogError(r, err)
+	}
+}
@@ -0,0 +1,319 @@
+package http
+
+import (
+	"cosmos"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// sessionCookie is the cookie in which the session token is stored after logging in.
+const sessionCookie = "cosmos_session"
+
+// registerLoginRoutes registers the routes which don't require authentication.
+func (s *Server) registerLoginRoutes(r *mux.Router) {
+	r.HandleFunc("/login", s.login).Methods("POST")
+}
+
+// registerAuthRoutes registers the routes for the current user and user management.
+// Every user can manage their own API tokens and change their own password.
+func (s *Server) registerAuthRoutes(r *mux.Router) {
+	r.HandleFunc("/logout", s.logout).Methods("POST")
+	r.HandleFunc("/me", s.getCurrentUser).Methods("GET")
+
+	r.HandleFunc("/tokens", s.findTokens).Methods("GET")
+	r.HandleFunc("/tokens", s.createToken).Methods("POST")
+	r.HandleFunc("/tokens/{id}", s.deleteToken).Methods("DELETE")
+
+	r.HandleFunc("/users", s.authorize(cosmos.RoleAdmin, nil, s.findUsers)).Methods("GET")
+	r.HandleFunc("/users", s.authorize(cosmos.RoleAdmin, nil, s.createUser)).Methods("POST")
+	r.HandleFunc("/users/{id}", s.updateUser).Methods("PATCH")
+	r.HandleFunc("/users/{id}", s.authorize(cosmos.RoleAdmin, nil, s.deleteUser)).Methods("DELETE")
+}
+
+// authMiddleware authenticates requests using the API token in the "Authorization: Bearer" header
+// or the session cookie set by logging in. The authenticated user is added to the request context.
+func (s *Server) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, token, err := s.App.Authenticate(r.Context(), requestToken(r))
+		if err != nil {
+			s.ReplyWithSanitizedError(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(cosmos.NewAuthContext(r.Context(), user, token)))
+	})
+}
+
+// requestToken returns the token that a request was made with.
+func requestToken(r *http.Request) string {
+	if v := r.Header.Get("Authorization"); strings.HasPrefix(v, "Bearer ") {
+		return strings.TrimPrefix(v, "Bearer ")
+	}
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	var credentials struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid JSON body"))
+		return
+	}
+
+	// Failed logins are throttled by the address of the client. Forwarded headers are not trusted
+	// since anyone could set them to get around the throttle.
+	clientIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		clientIP = r.RemoteAddr
+	}
+
+	secret, token, err := s.App.Login(r.Context(), credentials.Username, credentials.Password, clientIP)
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	// The session cookie is not sent with cross-site requests, which protects against CSRF.
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    secret,
+		Path:     "/",
+		Expires:  token.ExpiresAt,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	ret := map[string]interface{}{
+		"token":     secret,
+		"expiresAt": token.ExpiresAt,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&ret); err != nil {
+		s.LogError(r, err)
+	}
+}
+
+func (s *Server) logout(w http.ResponseWriter, r *http.Request) {
+	// Only sessions are removed on logout. API tokens must be deleted explicitly.
+	if token := cosmos.TokenFromContext(r.Context()); token.Session {
+		if err := s.App.DeleteToken(r.Context(), token.ID); err != nil {
+			s.ReplyWithSanitizedError(w, r, err)
+			return
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{}`))
+}
+
+func (s *Server) getCurrentUser(w http.ResponseWriter, r *http.Request) {
+	user := cosmos.UserFromContext(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		s.LogError(r, err)
+	}
+}
+
+func (s *Server) findTokens(w http.ResponseWriter, r *http.Request) {
+	// Users can only see their own API tokens.
+	user := cosmos.UserFromContext(r.Context())
+	session := false
+	tokens, totalTokens, err := s.App.FindTokens(r.Context(), cosmos.TokenFilter{UserID: &user.ID, Session: &session})
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	ret := map[string]interface{}{
+		"tokens":      tokens,
+		"totalTokens": totalTokens,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&ret); err != nil {
+		s.LogError(r, err)
+	}
+}
+
+func (s *Server) createToken(w http.ResponseWriter, r *http.Request) {
+	var token cosmos.Token
+	if err := json.NewDecoder(r.Body).Decode(&token); err != nil {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid JSON body"))
+		return
+	}
+
+	// API tokens are always created for the current user.
+	token.ID = 0
+	token.UserID = cosmos.UserFromContext(r.Context()).ID
+	token.Session = false
+	token.LastUsedAt = time.Time{}
+
+	secret, err := s.App.CreateToken(r.Context(), &token)
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	// The token is only ever returned in this response.
+	ret := map[string]interface{}{
+		"token":  secret,
+		"detail": &token,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(&ret); err != nil {
+		s.LogError(r, err)
+	}
+}
+
+func (s *Server) deleteToken(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid token ID"))
+		return
+	}
+
+	// Users can only delete their own tokens.
+	token, err := s.App.FindTokenByID(r.Context(), id)
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+	if token.UserID != cosmos.UserFromContext(r.Context()).ID {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.ENOTFOUND, "Token not found"))
+		return
+	}
+
+	if err := s.App.DeleteToken(r.Context(), id); err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{}`))
+}
+
+func (s *Server) findUsers(w http.ResponseWriter, r *http.Request) {
+	users, totalUsers, err := s.App.FindUsers(r.Context(), cosmos.UserFilter{})
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	ret := map[string]interface{}{
+		"users":      users,
+		"totalUsers": totalUsers,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&ret); err != nil {
+		s.LogError(r, err)
+	}
+}
+
+func (s *Server) createUser(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Role     string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid JSON body"))
+		return
+	}
+
+	user := &cosmos.User{Username: body.Username, Role: body.Role}
+	if err := s.App.CreateUser(r.Context(), user, body.Password); err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		s.LogError(r, err)
+	}
+}
+
+func (s *Server) updateUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid user ID"))
+		return
+	}
+
+	upd := &cosmos.UserUpdate{}
+	if err := json.NewDecoder(r.Body).Decode(upd); err != nil {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid JSON body"))
+		return
+	}
+
+	// Only admins can update other users and change roles. Admins cannot change their own role
+	// so that there is always at least one admin.
+	current := cosmos.UserFromContext(r.Context())
+	if id != current.ID || upd.Role != nil {
+		if err := s.App.Authorize(r.Context(), cosmos.RoleAdmin, nil); err != nil {
+			s.ReplyWithSanitizedError(w, r, err)
+			return
+		}
+	}
+	if id == current.ID && upd.Role != nil && *upd.Role != current.Role {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Users cannot change their own role"))
+		return
+	}
+
+	user, err := s.App.UpdateUser(r.Context(), id, upd)
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		s.LogError(r, err)
+	}
+}
+
+func (s *Server) deleteUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid user ID"))
+		return
+	}
+
+	// Prevent users from locking themselves out.
+	if id == cosmos.UserFromContext(r.Context()).ID {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Users cannot delete themselves"))
+		return
+	}
+
+	if err := s.App.DeleteUser(r.Context(), id); err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{}`))
+}
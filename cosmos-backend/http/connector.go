@@ -9,13 +9,13 @@ import (
 )
 
 func (s *Server) registerConnectorRoutes(r *mux.Router) {
-	r.HandleFunc("/connectors", s.findConnectors).Methods("GET")
-	r.HandleFunc("/connectors", s.createConnector).Methods("POST")
-	r.HandleFunc("/connectors/{id}", s.updateConnector).Methods("PATCH")
-	r.HandleFunc("/connectors/{id}", s.deleteConnector).Methods("DELETE")
+	r.HandleFunc("/connectors", s.authorize(cosmos.RoleNone, nil, s.findConnectors)).Methods("GET")
+	r.HandleFunc("/connectors", s.authorize(cosmos.RoleAdmin, nil, s.createConnector)).Methods("POST")
+	r.HandleFunc("/connectors/{id}", s.authorize(cosmos.RoleAdmin, nil, s.updateConnector)).Methods("PATCH")
+	r.HandleFunc("/connectors/{id}", s.authorize(cosmos.RoleAdmin, nil, s.deleteConnector)).Methods("DELETE")
 
-	r.HandleFunc("/connectors/{id}/connection-spec-form", s.connectionSpecForm).Methods("GET")
-	r.HandleFunc("/connectors/destination-types", s.getDestinationTypes).Methods("GET")
+	r.HandleFunc("/connectors/{id}/connection-spec-form", s.authorize(cosmos.RoleAdmin, nil, s.connectionSpecForm)).Methods("GET")
+	r.HandleFunc("/connectors/destination-types", s.authorize(cosmos.RoleNone, nil, s.getDestinationTypes)).Methods("GET")
 }
 
 func (s *Server) findConnectors(w http.ResponseWriter, r *http.Request) {
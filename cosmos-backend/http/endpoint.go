@@ -2,6 +2,7 @@ package http
 
 import (
 	"cosmos"
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -9,14 +10,16 @@ import (
 )
 
 func (s *Server) registerEndpointRoutes(r *mux.Router) {
-	r.HandleFunc("/endpoints", s.findEndpoints).Methods("GET")
-	r.HandleFunc("/endpoints", s.createEndpoint).Methods("POST")
-	r.HandleFunc("/endpoints/{id}", s.updateEndpoint).Methods("PATCH")
-	r.HandleFunc("/endpoints/{id}", s.deleteEndpoint).Methods("DELETE")
-
-	r.HandleFunc("/endpoints/{id}/edit-form", s.editEndpointForm).Methods("GET")
-	r.HandleFunc("/endpoints/{id}/rediscover", s.rediscoverEndpoint).Methods("POST")
-	r.HandleFunc("/endpoints/{srcID}/{dstID}/catalog-form", s.catalogForm).Methods("GET")
+	r.HandleFunc("/endpoints", s.authorize(cosmos.RoleNone, nil, s.findEndpoints)).Methods("GET")
+	r.HandleFunc("/endpoints", s.authorize(cosmos.RoleAdmin, nil, s.createEndpoint)).Methods("POST")
+	r.HandleFunc("/endpoints/{id}", s.authorize(cosmos.RoleAdmin, endpointScope, s.updateEndpoint)).Methods("PATCH")
+	r.HandleFunc("/endpoints/{id}", s.authorize(cosmos.RoleAdmin, endpointScope, s.deleteEndpoint)).Methods("DELETE")
+
+	r.HandleFunc("/endpoints/{id}/edit-form", s.authorize(cosmos.RoleAdmin, endpointScope, s.editEndpointForm)).Methods("GET")
+	r.HandleFunc("/endpoints/{id}/rediscover", s.authorize(cosmos.RoleAdmin, endpointScope, s.rediscoverEndpoint)).Methods("POST")
+	r.HandleFunc("/endpoints/{id}/preview", s.authorize(cosmos.RoleOperator, endpointScope, s.previewStream)).Methods("GET")
+	r.HandleFunc("/endpoints/{srcID}/{dstID}/catalog-form", s.authorize(cosmos.RoleAdmin, catalogFormScope("srcID"),
+		s.authorize(cosmos.RoleAdmin, catalogFormScope("dstID"), s.catalogForm))).Methods("GET")
 }
 
 func (s *Server) findEndpoints(w http.ResponseWriter, r *http.Request) {
@@ -26,12 +29,26 @@ func (s *Server) findEndpoints(w http.ResponseWriter, r *http.Request) {
 		filter.Type = &endpointType[0]
 	}
 
+	// Users who can't view all endpoints only see those they have permissions for.
+	scopes, err := s.App.PermittedScopes(r.Context(), cosmos.RoleViewer)
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+	if scopes != nil {
+		filter.IDs = scopes.EndpointIDs
+	}
+
 	endpoints, totalEndpoints, err := s.App.FindEndpoints(r.Context(), filter)
 	if err != nil {
 		s.ReplyWithSanitizedError(w, r, err)
 		return
 	}
 
+	for _, endpoint := range endpoints {
+		maskEndpoint(endpoint)
+	}
+
 	ret := map[string]interface{}{
 		"endpoints":      endpoints,
 		"totalEndpoints": totalEndpoints,
@@ -56,6 +73,8 @@ func (s *Server) createEndpoint(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	maskEndpoint(&endpoint)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	if err := json.NewEncoder(w).Encode(&endpoint); err != nil {
@@ -82,6 +101,8 @@ func (s *Server) updateEndpoint(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	maskEndpoint(endpoint)
+
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(endpoint); err != nil {
 		s.LogError(r, err)
@@ -121,7 +142,7 @@ func (s *Server) editEndpointForm(w http.ResponseWriter, r *http.Request) {
 	baseForm.Merge(&endpoint.Config)
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(baseForm); err != nil {
+	if err := json.NewEncoder(w).Encode(baseForm.Masked()); err != nil {
 		s.LogError(r, err)
 	}
 }
@@ -133,10 +154,23 @@ func (s *Server) rediscoverEndpoint(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.App.RediscoverEndpoint(r.Context(), id); err != nil {
+	failures, err := s.App.RediscoverEndpoint(r.Context(), id)
+	if err != nil {
 		s.ReplyWithSanitizedError(w, r, err)
 		return
 	}
+
+	// The catalog has been saved. Syncs which could not be updated are reported alongside.
+	for _, failure := range failures {
+		s.LogError(r, fmt.Errorf("failed to apply schema changes to sync %d: %w", failure.SyncID, failure.Err))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		FailedSyncs []*cosmos.SyncFailure `json:"failedSyncs"`
+	}{failures}); err != nil {
+		s.LogError(r, err)
+	}
 }
 
 func (s *Server) catalogForm(w http.ResponseWriter, r *http.Request) {
@@ -151,6 +185,24 @@ func (s *Server) catalogForm(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The role for a sync only gives access to the catalog form of its own endpoints.
+	if v := r.URL.Query().Get("syncID"); v != "" {
+		syncID, err := strconv.Atoi(v)
+		if err != nil {
+			s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid sync ID"))
+			return
+		}
+		sync, err := s.App.FindSyncByID(r.Context(), syncID)
+		if err != nil {
+			s.ReplyWithSanitizedError(w, r, err)
+			return
+		}
+		if sync.SourceEndpointID != srcID || sync.DestinationEndpointID != dstID {
+			s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "The endpoints are not those of sync %s", sync.Name))
+			return
+		}
+	}
+
 	srcEndpoint, err := s.App.FindEndpointByID(r.Context(), srcID)
 	if err != nil {
 		s.ReplyWithSanitizedError(w, r, err)
@@ -173,3 +225,45 @@ func (s *Server) catalogForm(w http.ResponseWriter, r *http.Request) {
 		s.LogError(r, err)
 	}
 }
+
+// previewStream returns the first few records of the stream given in the "stream" query parameter.
+// The stream is identified by its name, prefixed with its namespace (if any) and a dot.
+func (s *Server) previewStream(w http.ResponseWriter, r *http.Request) {
+	endpointID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid endpoint ID"))
+		return
+	}
+
+	stream := r.URL.Query().Get("stream")
+	if stream == "" {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Stream is required"))
+		return
+	}
+
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid limit"))
+			return
+		}
+	}
+
+	preview, err := s.App.PreviewStream(r.Context(), endpointID, stream, limit)
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(preview); err != nil {
+		s.LogError(r, err)
+	}
+}
+
+// maskEndpoint removes the values of the secrets from the config of an endpoint before it is returned.
+func maskEndpoint(endpoint *cosmos.Endpoint) {
+	if endpoint != nil {
+		endpoint.Config = *endpoint.Config.Masked()
+	}
+}
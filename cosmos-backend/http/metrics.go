@@ -0,0 +1,56 @@
+package http
+
+import (
+	"context"
+	"cosmos"
+	"net"
+	"net/http"
+	"time"
+)
+
+// MetricsServer serves the metrics of a daemon for prometheus to scrape. It listens on an address of
+// its own, so that the metrics (which include the IDs of syncs and the names of streams) aren't
+// exposed on the public API listener.
+type MetricsServer struct {
+	listener net.Listener
+	server   *http.Server
+
+	Addr string
+
+	*cosmos.App
+}
+
+// NewMetricsServer returns a new instance of MetricsServer.
+func NewMetricsServer(addr string) *MetricsServer {
+	s := &MetricsServer{
+		server: &http.Server{},
+		Addr:   addr,
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.App.MetricsHandler().ServeHTTP(w, r)
+	}))
+	s.server.Handler = mux
+
+	return s
+}
+
+// Open begins listening on the bind address.
+func (s *MetricsServer) Open() (err error) {
+	if s.listener, err = net.Listen("tcp", s.Addr); err != nil {
+		return err
+	}
+	go s.server.Serve(s.listener)
+	return nil
+}
+
+// Close gracefully shuts down the metrics server.
+func (s *MetricsServer) Close() error {
+	if s.listener != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return s.server.Shutdown(ctx)
+	}
+	return nil
+}
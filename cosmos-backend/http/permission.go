@@ -0,0 +1,155 @@
+package http
+
+import (
+	"cosmos"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+func (s *Server) registerPermissionRoutes(r *mux.Router) {
+	r.HandleFunc("/permissions", s.authorize(cosmos.RoleAdmin, nil, s.findPermissions)).Methods("GET")
+	r.HandleFunc("/permissions", s.authorize(cosmos.RoleAdmin, nil, s.createPermission)).Methods("POST")
+	r.HandleFunc("/permissions/{id}", s.authorize(cosmos.RoleAdmin, nil, s.deletePermission)).Methods("DELETE")
+}
+
+// scopeFunc returns the sync or endpoint that a request acts on.
+type scopeFunc func(r *http.Request) (*cosmos.Scope, error)
+
+// authorize wraps a handler so that it is only called if the authenticated user has the required role,
+// either for all syncs and endpoints or for the sync or endpoint returned by scope (if any).
+func (s *Server) authorize(role string, scope scopeFunc, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var sc *cosmos.Scope
+		if scope != nil {
+			var err error
+			if sc, err = scope(r); err != nil {
+				s.ReplyWithSanitizedError(w, r, err)
+				return
+			}
+		}
+
+		if err := s.App.Authorize(r.Context(), role, sc); err != nil {
+			s.ReplyWithSanitizedError(w, r, err)
+			return
+		}
+
+		h(w, r)
+	}
+}
+
+// syncScope is the sync with the ID in the "id" path variable.
+func syncScope(r *http.Request) (*cosmos.Scope, error) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, cosmos.Errorf(cosmos.EINVALID, "Invalid sync ID")
+	}
+	return &cosmos.Scope{SyncID: &id}, nil
+}
+
+// endpointScope is the endpoint with the ID in the "id" path variable.
+func endpointScope(r *http.Request) (*cosmos.Scope, error) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, cosmos.Errorf(cosmos.EINVALID, "Invalid endpoint ID")
+	}
+	return &cosmos.Scope{EndpointID: &id}, nil
+}
+
+// catalogFormScope is the sync in the "syncID" query parameter when the catalog form is for an
+// existing sync, or the endpoint with the ID in the given path variable otherwise.
+func catalogFormScope(key string) scopeFunc {
+	return func(r *http.Request) (*cosmos.Scope, error) {
+		if v := r.URL.Query().Get("syncID"); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, cosmos.Errorf(cosmos.EINVALID, "Invalid sync ID")
+			}
+			return &cosmos.Scope{SyncID: &id}, nil
+		}
+		id, err := strconv.Atoi(mux.Vars(r)[key])
+		if err != nil {
+			return nil, cosmos.Errorf(cosmos.EINVALID, "Invalid endpoint ID")
+		}
+		return &cosmos.Scope{EndpointID: &id}, nil
+	}
+}
+
+// runScope returns the sync of the run with the ID in the given path variable.
+func (s *Server) runScope(key string) scopeFunc {
+	return func(r *http.Request) (*cosmos.Scope, error) {
+		id, err := strconv.Atoi(mux.Vars(r)[key])
+		if err != nil {
+			return nil, cosmos.Errorf(cosmos.EINVALID, "Invalid run ID")
+		}
+		run, err := s.App.FindRunByID(r.Context(), id)
+		if err != nil {
+			return nil, err
+		}
+		return &cosmos.Scope{SyncID: &run.SyncID}, nil
+	}
+}
+
+func (s *Server) findPermissions(w http.ResponseWriter, r *http.Request) {
+	filter := cosmos.PermissionFilter{}
+	if v := r.URL.Query().Get("userID"); v != "" {
+		userID, err := strconv.Atoi(v)
+		if err != nil {
+			s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid user ID"))
+			return
+		}
+		filter.UserID = &userID
+	}
+
+	permissions, totalPermissions, err := s.App.FindPermissions(r.Context(), filter)
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	ret := map[string]interface{}{
+		"permissions":      permissions,
+		"totalPermissions": totalPermissions,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&ret); err != nil {
+		s.LogError(r, err)
+	}
+}
+
+func (s *Server) createPermission(w http.ResponseWriter, r *http.Request) {
+	var permission cosmos.Permission
+	if err := json.NewDecoder(r.Body).Decode(&permission); err != nil {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid JSON body"))
+		return
+	}
+
+	if err := s.App.CreatePermission(r.Context(), &permission); err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(&permission); err != nil {
+		s.LogError(r, err)
+	}
+}
+
+func (s *Server) deletePermission(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid permission ID"))
+		return
+	}
+
+	if err := s.App.DeletePermission(r.Context(), id); err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{}`))
+}
@@ -1,7 +1,9 @@
 package http
 
 import (
+	"bytes"
 	"cosmos"
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -9,10 +11,15 @@ import (
 )
 
 func (s *Server) registerRunRoutes(r *mux.Router) {
-	r.HandleFunc("/runs", s.findRuns).Methods("POST")
-	r.HandleFunc("/runs/{id}", s.findRuns).Methods("GET")
+	r.HandleFunc("/runs", s.authorize(cosmos.RoleNone, nil, s.findRuns)).Methods("POST")
+	r.HandleFunc("/runs/{id}", s.authorize(cosmos.RoleViewer, s.runScope("id"), s.findRuns)).Methods("GET")
 
-	r.HandleFunc("/runs/{id}/cancel", s.cancelRun).Methods("POST")
+	r.HandleFunc("/runs/{id}/cancel", s.authorize(cosmos.RoleOperator, s.runScope("id"), s.cancelRun)).Methods("POST")
+	r.HandleFunc("/runs/{id}/quarantine", s.authorize(cosmos.RoleViewer, s.runScope("id"), s.getQuarantinedRecords)).Methods("GET")
+	r.HandleFunc("/runs/{id}/artifacts", s.authorize(cosmos.RoleViewer, s.runScope("id"), s.listArtifacts)).Methods("GET")
+	r.HandleFunc("/runs/{id}/bundle", s.authorize(cosmos.RoleViewer, s.runScope("id"), s.getRunBundle)).Methods("GET")
+	r.HandleFunc("/runs/{id}/logs", s.authorize(cosmos.RoleViewer, s.runScope("id"), s.searchLogs)).Methods("POST")
+	r.HandleFunc("/runs/{id}/timeline", s.authorize(cosmos.RoleViewer, s.runScope("id"), s.getRunTimeline)).Methods("GET")
 }
 
 func (s *Server) findRuns(w http.ResponseWriter, r *http.Request) {
@@ -35,12 +42,26 @@ func (s *Server) findRuns(w http.ResponseWriter, r *http.Request) {
 		panic("Unhandled request method in findRuns")
 	}
 
+	// Users who can't view all syncs only see the runs of those they have permissions for.
+	scopes, err := s.App.PermittedScopes(r.Context(), cosmos.RoleViewer)
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+	if scopes != nil {
+		filter.SyncIDs = scopes.SyncIDs
+	}
+
 	runs, totalRuns, err := s.App.FindRuns(r.Context(), filter)
 	if err != nil {
 		s.ReplyWithSanitizedError(w, r, err)
 		return
 	}
 
+	for _, run := range runs {
+		maskSync(run.Sync)
+	}
+
 	ret := map[string]interface{}{
 		"runs":      runs,
 		"totalRuns": totalRuns,
@@ -63,3 +84,164 @@ func (s *Server) cancelRun(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+func (s *Server) getQuarantinedRecords(w http.ResponseWriter, r *http.Request) {
+	runID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid run ID"))
+		return
+	}
+
+	run, err := s.App.FindRunByID(r.Context(), runID)
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	artifactory, err := s.App.GetRunArtifactory(run)
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	data, err := s.App.GetArtifactData(artifactory, cosmos.ArtifactQuarantine)
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	// Each line of the quarantine artifact holds a quarantined record as its message.
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="run-%d-quarantine.jsonl"`, run.ID))
+	for _, b := range bytes.Split(data, []byte("\n")) {
+		if len(b) != 0 {
+			line, _ := cosmos.ParseLogLine(b)
+			fmt.Fprintln(w, line.Message)
+		}
+	}
+}
+
+func (s *Server) listArtifacts(w http.ResponseWriter, r *http.Request) {
+	runID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid run ID"))
+		return
+	}
+
+	run, err := s.App.FindRunByID(r.Context(), runID)
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	artifactory, err := s.App.GetRunArtifactory(run)
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	artifacts, err := s.App.ListArtifacts(artifactory)
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	ret := map[string]interface{}{
+		"artifacts": artifacts,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&ret); err != nil {
+		s.LogError(r, err)
+	}
+}
+
+func (s *Server) getRunBundle(w http.ResponseWriter, r *http.Request) {
+	runID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid run ID"))
+		return
+	}
+
+	run, err := s.App.FindRunByID(r.Context(), runID)
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	// The bundle is built in memory so that errors can still be reported to the user.
+	var buf bytes.Buffer
+	if err := s.App.WriteRunBundle(r.Context(), run, &buf); err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="run-%d.tar.gz"`, run.ID))
+	w.Write(buf.Bytes())
+}
+
+func (s *Server) searchLogs(w http.ResponseWriter, r *http.Request) {
+	runID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid run ID"))
+		return
+	}
+
+	filter := cosmos.LogFilter{}
+	if err := json.NewDecoder(r.Body).Decode(&filter); err != nil {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid JSON body"))
+		return
+	}
+
+	run, err := s.App.FindRunByID(r.Context(), runID)
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	lines, totalLines, err := s.App.SearchLogs(r.Context(), run, filter)
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	ret := map[string]interface{}{
+		"lines":      lines,
+		"totalLines": totalLines,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&ret); err != nil {
+		s.LogError(r, err)
+	}
+}
+
+func (s *Server) getRunTimeline(w http.ResponseWriter, r *http.Request) {
+	runID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid run ID"))
+		return
+	}
+
+	run, err := s.App.FindRunByID(r.Context(), runID)
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	timeline, err := s.App.RunTimeline(r.Context(), run)
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	ret := map[string]interface{}{
+		"events": timeline,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&ret); err != nil {
+		s.LogError(r, err)
+	}
+}
@@ -14,18 +14,34 @@ import (
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	jsoniter "github.com/json-iterator/go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var json = jsoniter.ConfigDefault
 
+var tracer = otel.Tracer("cosmos/http")
+
 // Server represents a HTTP server.
 type Server struct {
 	listener net.Listener
 	server   *http.Server
 	router   *mux.Router
 
+	// done is closed when the server is shutting down so that long-lived
+	// streaming responses can be terminated.
+	done chan struct{}
+
 	Addr string
 
+	// TLSCertFile and TLSKeyFile are the certificate and key that the server is
+	// served with over HTTPS. The server is served over plain HTTP if they are empty.
+	TLSCertFile string
+	TLSKeyFile  string
+
 	*cosmos.App
 }
 
@@ -71,30 +87,53 @@ func (h spaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	http.FileServer(http.Dir(h.staticPath)).ServeHTTP(w, r)
 }
 
-// NewServer returns a new instance of Server.
-func NewServer(addr string) *Server {
+// NewServer returns a new instance of Server. Cross-origin requests
+// are only allowed from the given origins.
+func NewServer(addr string, allowedOrigins []string) *Server {
 	s := &Server{
 		server: &http.Server{},
 		router: mux.NewRouter(),
+		done:   make(chan struct{}),
 		Addr:   addr,
 	}
 
 	// Delegate HTTP handling to the Gorilla router.
 	// Allow CORS (See https://www.thepolyglotdeveloper.com/2017/10/handling-cors-golang-web-application/).
-	s.server.Handler = handlers.CORS(
-		handlers.AllowedHeaders([]string{"X-Requested-With", "Content-Type", "Authorization"}),
-		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}),
-		handlers.AllowedOrigins([]string{"*"}),
-	)(s.router)
+	// Credentials are allowed so that the session cookie is sent by browsers. Hence, the
+	// allowed origins must be listed explicitly. The gorilla handler allows any origin otherwise.
+	s.server.Handler = s.router
+	if len(allowedOrigins) > 0 {
+		s.server.Handler = handlers.CORS(
+			handlers.AllowedHeaders([]string{"X-Requested-With", "Content-Type", "Authorization", "Last-Event-ID", "traceparent", "tracestate", workspaceHeader}),
+			handlers.AllowedMethods([]string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}),
+			handlers.AllowedOrigins(allowedOrigins),
+			handlers.AllowCredentials(),
+		)(s.router)
+	}
 
 	// Register routes.
 	r := s.router.PathPrefix("/api/v1").Subrouter()
+	r.Use(s.metricsMiddleware)
+	r.Use(tracingMiddleware)
 	r.Use(recoveryMiddleware)
+	s.registerLoginRoutes(r)
+
+	// All other routes require authentication.
+	r = r.NewRoute().Subrouter()
+	r.Use(s.authMiddleware)
+	s.registerAuthRoutes(r)
+	s.registerWorkspaceRoutes(r)
+	s.registerAuditRoutes(r)
+
+	// All other routes are scoped to a workspace.
+	r = r.NewRoute().Subrouter()
+	r.Use(s.workspaceMiddleware)
 	s.registerConnectorRoutes(r)
 	s.registerEndpointRoutes(r)
 	s.registerSyncRoutes(r)
 	s.registerRunRoutes(r)
 	s.registerArtifactRoutes(r)
+	s.registerPermissionRoutes(r)
 
 	// Serve SPA (Single Page Application).
 	// See https://github.com/gorilla/mux#serving-single-page-applications
@@ -119,6 +158,69 @@ func recoveryMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// statusRecorder records the status code of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (w *statusRecorder) WriteHeader(code int) {
+	w.code = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Flush allows log artifacts to be streamed through the recorder.
+func (w *statusRecorder) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// metricsMiddleware records the duration and status code of requests by route.
+func (s *Server) metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+
+		next.ServeHTTP(rec, r)
+
+		s.App.ObserveHTTPRequest(routeTemplate(r), r.Method, rec.code, time.Since(start))
+	})
+}
+
+// tracingMiddleware starts a span for each request. The span is a child of the
+// span in the W3C trace context headers of the request (if any).
+func tracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		route := routeTemplate(r)
+		ctx, span := tracer.Start(ctx, r.Method+" "+route, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+			attribute.String("http.method", r.Method),
+			attribute.String("http.route", route),
+		))
+		defer span.End()
+
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		span.SetAttributes(attribute.Int("http.status_code", rec.code))
+		if rec.code >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rec.code))
+		}
+	})
+}
+
+// routeTemplate returns the template of the route of a request. It is used instead of the
+// path in metrics and spans so that IDs don't end up in the labels.
+func routeTemplate(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return "unknown"
+}
+
 // Open begins listening on the bind address.
 func (s *Server) Open() (err error) {
 	if s.listener, err = net.Listen("tcp", s.Addr); err != nil {
@@ -128,14 +230,25 @@ func (s *Server) Open() (err error) {
 	// Begin serving requests on a listener. We use Serve() instead of
 	// ListenAndServe() because it allows us to check for listener errors
 	// (such as trying to use an already open port) synchronously.
-	go s.server.Serve(s.listener)
+	if s.UseTLS() {
+		go s.server.ServeTLS(s.listener, s.TLSCertFile, s.TLSKeyFile)
+	} else {
+		go s.server.Serve(s.listener)
+	}
 
 	return nil
 }
 
+// UseTLS returns true if the server is served over HTTPS.
+func (s *Server) UseTLS() bool {
+	return s.TLSCertFile != "" && s.TLSKeyFile != ""
+}
+
 // Close gracefully shuts down the HTTP server.
 func (s *Server) Close() error {
 	if s.listener != nil {
+		close(s.done)
+
 		// Allow 30 seconds for the server to shutdown cleanly.
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
@@ -177,11 +290,15 @@ type ErrorResponse struct {
 // ErrorStatusCode returns the HTTP status code that matches the application-specific error code.
 func ErrorStatusCode(code string) int {
 	codes := map[string]int{
-		cosmos.EINVALID:        http.StatusBadRequest,
-		cosmos.EINTERNAL:       http.StatusInternalServerError,
-		cosmos.ECONFLICT:       http.StatusConflict,
-		cosmos.ENOTFOUND:       http.StatusNotFound,
-		cosmos.ENOTIMPLEMENTED: http.StatusNotImplemented,
+		cosmos.EINVALID:         http.StatusBadRequest,
+		cosmos.EINTERNAL:        http.StatusInternalServerError,
+		cosmos.ECONFLICT:        http.StatusConflict,
+		cosmos.ENOTFOUND:        http.StatusNotFound,
+		cosmos.ENOTIMPLEMENTED:  http.StatusNotImplemented,
+		cosmos.EPURGED:          http.StatusGone,
+		cosmos.EUNAUTHORIZED:    http.StatusUnauthorized,
+		cosmos.EFORBIDDEN:       http.StatusForbidden,
+		cosmos.ETOOMANYREQUESTS: http.StatusTooManyRequests,
 	}
 
 	if statusCode, ok := codes[code]; ok {
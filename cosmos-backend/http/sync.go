@@ -9,14 +9,15 @@ import (
 )
 
 func (s *Server) registerSyncRoutes(r *mux.Router) {
-	r.HandleFunc("/syncs", s.findSyncs).Methods("GET")
-	r.HandleFunc("/syncs/{id}", s.findSyncs).Methods("GET")
-	r.HandleFunc("/syncs", s.createSync).Methods("POST")
-	r.HandleFunc("/syncs/{id}", s.updateSync).Methods("PATCH")
-	r.HandleFunc("/syncs/{id}", s.deleteSync).Methods("DELETE")
-
-	r.HandleFunc("/syncs/{id}/edit-form", s.editSyncForm).Methods("GET")
-	r.HandleFunc("/syncs/{id}/sync-now", s.syncNow).Methods("POST")
+	r.HandleFunc("/syncs", s.authorize(cosmos.RoleNone, nil, s.findSyncs)).Methods("GET")
+	r.HandleFunc("/syncs/{id}", s.authorize(cosmos.RoleViewer, syncScope, s.findSyncs)).Methods("GET")
+	r.HandleFunc("/syncs", s.authorize(cosmos.RoleAdmin, nil, s.createSync)).Methods("POST")
+	r.HandleFunc("/syncs/{id}", s.authorize(cosmos.RoleAdmin, syncScope, s.updateSync)).Methods("PATCH")
+	r.HandleFunc("/syncs/{id}", s.authorize(cosmos.RoleAdmin, syncScope, s.deleteSync)).Methods("DELETE")
+
+	r.HandleFunc("/syncs/{id}/edit-form", s.authorize(cosmos.RoleAdmin, syncScope, s.editSyncForm)).Methods("GET")
+	r.HandleFunc("/syncs/{id}/sync-now", s.authorize(cosmos.RoleOperator, syncScope, s.syncNow)).Methods("POST")
+	r.HandleFunc("/syncs/{id}/schema-changes", s.authorize(cosmos.RoleViewer, syncScope, s.getSchemaChanges)).Methods("GET")
 }
 
 func (s *Server) findSyncs(w http.ResponseWriter, r *http.Request) {
@@ -31,12 +32,26 @@ func (s *Server) findSyncs(w http.ResponseWriter, r *http.Request) {
 		filter.ID = &syncID
 	}
 
+	// Users who can't view all syncs only see those they have permissions for.
+	scopes, err := s.App.PermittedScopes(r.Context(), cosmos.RoleViewer)
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+	if scopes != nil {
+		filter.IDs = scopes.SyncIDs
+	}
+
 	syncs, totalSyncs, err := s.App.FindSyncs(r.Context(), filter)
 	if err != nil {
 		s.ReplyWithSanitizedError(w, r, err)
 		return
 	}
 
+	for _, sync := range syncs {
+		maskSync(sync)
+	}
+
 	ret := map[string]interface{}{
 		"syncs":      syncs,
 		"totalSyncs": totalSyncs,
@@ -61,6 +76,8 @@ func (s *Server) createSync(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	maskSync(&sync)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	if err := json.NewEncoder(w).Encode(&sync); err != nil {
@@ -87,6 +104,8 @@ func (s *Server) updateSync(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	maskSync(sync)
+
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(sync); err != nil {
 		s.LogError(r, err)
@@ -148,8 +167,47 @@ func (s *Server) syncNow(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.App.Schedule(&syncID, runOptions); err != nil {
+	if err := s.App.Schedule(r.Context(), syncID, runOptions); err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+}
+
+func (s *Server) getSchemaChanges(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid sync ID"))
+		return
+	}
+
+	sync, err := s.App.FindSyncByID(r.Context(), id)
+	if err != nil {
 		s.ReplyWithSanitizedError(w, r, err)
 		return
 	}
+
+	schemaChanges := sync.SchemaChanges
+	if schemaChanges == nil {
+		schemaChanges = []*cosmos.SchemaChange{}
+	}
+
+	ret := map[string]interface{}{
+		"schemaChangePolicy": sync.SchemaChangePolicy,
+		"schemaChanges":      schemaChanges,
+		"breaking":           cosmos.HasBreakingSchemaChanges(schemaChanges),
+		"lastDiscovered":     sync.SourceEndpoint.LastDiscovered,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&ret); err != nil {
+		s.LogError(r, err)
+	}
+}
+
+// maskSync removes the values of the secrets from the configs of the endpoints of a sync before it is returned.
+func maskSync(sync *cosmos.Sync) {
+	if sync != nil {
+		maskEndpoint(sync.SourceEndpoint)
+		maskEndpoint(sync.DestinationEndpoint)
+	}
 }
@@ -0,0 +1,215 @@
+package http
+
+import (
+	"cosmos"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// workspaceHeader names the workspace that a request is scoped to.
+const workspaceHeader = "X-Workspace-ID"
+
+func (s *Server) registerWorkspaceRoutes(r *mux.Router) {
+	r.HandleFunc("/workspaces", s.authorize(cosmos.RoleNone, nil, s.findWorkspaces)).Methods("GET")
+	r.HandleFunc("/workspaces", s.authorize(cosmos.RoleAdmin, nil, s.createWorkspace)).Methods("POST")
+	r.HandleFunc("/workspaces/{id}", s.authorize(cosmos.RoleAdmin, nil, s.updateWorkspace)).Methods("PATCH")
+	r.HandleFunc("/workspaces/{id}", s.authorize(cosmos.RoleAdmin, nil, s.deleteWorkspace)).Methods("DELETE")
+
+	r.HandleFunc("/workspaces/{id}/members", s.authorize(cosmos.RoleAdmin, nil, s.findWorkspaceMembers)).Methods("GET")
+	r.HandleFunc("/workspaces/{id}/members", s.authorize(cosmos.RoleAdmin, nil, s.createWorkspaceMember)).Methods("POST")
+	r.HandleFunc("/workspaces/{id}/members/{memberID}", s.authorize(cosmos.RoleAdmin, nil, s.deleteWorkspaceMember)).Methods("DELETE")
+}
+
+// workspaceMiddleware scopes requests to the workspace in the "X-Workspace-ID" header, or to the
+// default workspace if the header isn't set. Only the members of a workspace can access it.
+func (s *Server) workspaceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		workspaceID := cosmos.DefaultWorkspaceID
+		if v := r.Header.Get(workspaceHeader); v != "" {
+			var err error
+			if workspaceID, err = strconv.Atoi(v); err != nil {
+				s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid workspace ID"))
+				return
+			}
+		}
+		ctx := cosmos.NewWorkspaceContext(r.Context(), workspaceID)
+
+		// Check the membership first, so that users don't learn which other workspaces exist.
+		if err := s.App.AuthorizeWorkspace(ctx); err != nil {
+			s.ReplyWithSanitizedError(w, r, err)
+			return
+		}
+		if _, err := s.App.FindWorkspaceByID(ctx, workspaceID); err != nil {
+			s.ReplyWithSanitizedError(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// findWorkspaces returns the workspaces that the authenticated user is a member of.
+func (s *Server) findWorkspaces(w http.ResponseWriter, r *http.Request) {
+	filter := cosmos.WorkspaceFilter{}
+	if user := cosmos.UserFromContext(r.Context()); !cosmos.HasRole(user.Role, cosmos.RoleAdmin) {
+		filter.UserID = &user.ID
+	}
+
+	workspaces, totalWorkspaces, err := s.App.FindWorkspaces(r.Context(), filter)
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	ret := map[string]interface{}{
+		"workspaces":      workspaces,
+		"totalWorkspaces": totalWorkspaces,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&ret); err != nil {
+		s.LogError(r, err)
+	}
+}
+
+func (s *Server) createWorkspace(w http.ResponseWriter, r *http.Request) {
+	var workspace cosmos.Workspace
+	if err := json.NewDecoder(r.Body).Decode(&workspace); err != nil {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid JSON body"))
+		return
+	}
+
+	if err := s.App.CreateWorkspace(r.Context(), &workspace); err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(&workspace); err != nil {
+		s.LogError(r, err)
+	}
+}
+
+func (s *Server) updateWorkspace(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid workspace ID"))
+		return
+	}
+
+	upd := &cosmos.WorkspaceUpdate{}
+	if err := json.NewDecoder(r.Body).Decode(upd); err != nil {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid JSON body"))
+		return
+	}
+
+	workspace, err := s.App.UpdateWorkspace(r.Context(), id, upd)
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(workspace); err != nil {
+		s.LogError(r, err)
+	}
+}
+
+func (s *Server) deleteWorkspace(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid workspace ID"))
+		return
+	}
+
+	if err := s.App.DeleteWorkspace(r.Context(), id); err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{}`))
+}
+
+func (s *Server) findWorkspaceMembers(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid workspace ID"))
+		return
+	}
+
+	members, totalMembers, err := s.App.FindWorkspaceMembers(r.Context(), cosmos.WorkspaceMemberFilter{WorkspaceID: &id})
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	ret := map[string]interface{}{
+		"members":      members,
+		"totalMembers": totalMembers,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&ret); err != nil {
+		s.LogError(r, err)
+	}
+}
+
+func (s *Server) createWorkspaceMember(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid workspace ID"))
+		return
+	}
+
+	var member cosmos.WorkspaceMember
+	if err := json.NewDecoder(r.Body).Decode(&member); err != nil {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid JSON body"))
+		return
+	}
+	member.WorkspaceID = id
+
+	if err := s.App.CreateWorkspaceMember(r.Context(), &member); err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(&member); err != nil {
+		s.LogError(r, err)
+	}
+}
+
+func (s *Server) deleteWorkspaceMember(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid workspace ID"))
+		return
+	}
+	memberID, err := strconv.Atoi(mux.Vars(r)["memberID"])
+	if err != nil {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.EINVALID, "Invalid member ID"))
+		return
+	}
+
+	// The member must belong to the workspace in the path.
+	if member, err := s.App.FindWorkspaceMemberByID(r.Context(), memberID); err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	} else if member.WorkspaceID != id {
+		s.ReplyWithSanitizedError(w, r, cosmos.Errorf(cosmos.ENOTFOUND, "Workspace member not found"))
+		return
+	}
+
+	if err := s.App.DeleteWorkspaceMember(r.Context(), memberID); err != nil {
+		s.ReplyWithSanitizedError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{}`))
+}
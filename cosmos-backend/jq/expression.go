@@ -0,0 +1,143 @@
+package jq
+
+import (
+	"context"
+	"cosmos"
+	"strings"
+
+	"github.com/itchyny/gojq"
+)
+
+var _ cosmos.ExpressionService = (*ExpressionService)(nil)
+
+// ExpressionService compiles jq expressions (see https://stedolan.github.io/jq/manual/).
+type ExpressionService struct {
+}
+
+func NewExpressionService() *ExpressionService {
+	return &ExpressionService{}
+}
+
+func (s *ExpressionService) CompileExpression(ctx context.Context, expr string) (cosmos.Expression, error) {
+	query, err := gojq.Parse(expr)
+	if err != nil {
+		return nil, cosmos.Errorf(cosmos.EINVALID, err.Error())
+	}
+
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return nil, cosmos.Errorf(cosmos.EINVALID, err.Error())
+	}
+
+	return &Expression{code: code}, nil
+}
+
+// ProjectedFields supports projections which build an object with fixed keys, e.g,
+// {id, contact: .email, full: (.first + " " + .last), amount: (.cents / 100)}.
+// Values which are fields of the record keep their path, so that their schema can be derived.
+func (s *ExpressionService) ProjectedFields(ctx context.Context, expr string) ([]cosmos.ProjectedField, error) {
+	query, err := gojq.Parse(expr)
+	if err != nil {
+		return nil, cosmos.Errorf(cosmos.EINVALID, err.Error())
+	}
+
+	errUnsupported := cosmos.Errorf(cosmos.EINVALID, "projection must build an object with fixed keys, e.g, {id, contact: .email, amount: (.cents / 100)}")
+
+	if !isTerm(query) || query.Term.Type != gojq.TermTypeObject || len(query.Term.SuffixList) != 0 {
+		return nil, errUnsupported
+	}
+
+	fields := []cosmos.ProjectedField{}
+	for _, kv := range query.Term.Object.KeyVals {
+		var field cosmos.ProjectedField
+
+		switch {
+		case kv.KeyOnly != "" && !strings.HasPrefix(kv.KeyOnly, "$"):
+			// {id} is short for {id: .id}.
+			field = cosmos.ProjectedField{Name: kv.KeyOnly, Path: []string{kv.KeyOnly}}
+		case kv.Key != "" && !strings.HasPrefix(kv.Key, "$"), kv.KeyString != nil && len(kv.KeyString.Queries) == 0:
+			field.Name = kv.Key
+			if kv.KeyString != nil {
+				field.Name = kv.KeyString.Str
+			}
+			if kv.Val == nil {
+				return nil, errUnsupported
+			}
+			// Any other value is computed by an expression.
+			if len(kv.Val.Queries) == 1 && isTerm(kv.Val.Queries[0]) {
+				field.Path, _ = fieldPath(kv.Val.Queries[0].Term)
+			}
+		default:
+			return nil, errUnsupported
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+// isTerm returns true if the query consists of a single term.
+func isTerm(query *gojq.Query) bool {
+	return query.Term != nil && query.Left == nil && query.Right == nil &&
+		len(query.FuncDefs) == 0 && len(query.Imports) == 0 && query.Meta == nil
+}
+
+// fieldPath returns the path of the field referenced by a term like .address.city.
+func fieldPath(term *gojq.Term) ([]string, bool) {
+	if term.Type != gojq.TermTypeIndex {
+		return nil, false
+	}
+
+	name, ok := indexName(term.Index)
+	if !ok {
+		return nil, false
+	}
+	path := []string{name}
+
+	for _, suffix := range term.SuffixList {
+		if suffix.Index == nil || suffix.Iter || suffix.Optional || suffix.Bind != nil {
+			return nil, false
+		}
+		name, ok := indexName(suffix.Index)
+		if !ok {
+			return nil, false
+		}
+		path = append(path, name)
+	}
+
+	return path, true
+}
+
+// indexName returns the name of the field referenced by an index like .city or ."city name".
+func indexName(index *gojq.Index) (string, bool) {
+	switch {
+	case index == nil || index.IsSlice || index.Start != nil:
+		return "", false
+	case index.Name != "":
+		return index.Name, true
+	case index.Str != nil && len(index.Str.Queries) == 0:
+		return index.Str.Str, true
+	}
+	return "", false
+}
+
+// Expression is a compiled jq expression.
+type Expression struct {
+	code *gojq.Code
+}
+
+// Evaluate returns the first value produced by the jq expression, or cosmos.ErrNoOutput if it doesn't produce any.
+func (e *Expression) Evaluate(data map[string]interface{}) (interface{}, error) {
+	iter := e.code.Run(data)
+
+	v, ok := iter.Next()
+	if !ok {
+		return nil, cosmos.ErrNoOutput
+	}
+	if err, ok := v.(error); ok {
+		return nil, err
+	}
+
+	return v, nil
+}
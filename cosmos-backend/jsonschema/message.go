@@ -94,13 +94,7 @@ func (s *MessageService) CreateMessage(ctx context.Context, raw []byte) (*cosmos
 	}
 
 	if !result.Valid() {
-		msg := strings.Builder{}
-		for _, e := range result.Errors() {
-			if !strings.HasPrefix(e.Description(), "Must validate") {
-				msg.WriteString(fmt.Sprintf("%s\n\n", e))
-			}
-		}
-		return nil, cosmos.Errorf(cosmos.EINVALID, msg.String())
+		return nil, cosmos.Errorf(cosmos.EINVALID, resultErrors(result))
 	}
 
 	msg := &cosmos.Message{}
@@ -137,18 +131,49 @@ func (s *MessageService) Validate(ctx context.Context, raw interface{}, message
 	}
 
 	if !result.Valid() {
-		msg := strings.Builder{}
-		for _, e := range result.Errors() {
-			if !strings.HasPrefix(e.Description(), "Must validate") {
-				msg.WriteString(fmt.Sprintf("%s\n\n", e))
-			}
-		}
-		return cosmos.Errorf(cosmos.EINVALID, msg.String())
+		return cosmos.Errorf(cosmos.EINVALID, resultErrors(result))
+	}
+
+	return nil
+}
+
+// recordValidator validates record data against the pre-compiled JSON schema of a stream.
+type recordValidator struct {
+	schema *js.Schema
+}
+
+func (s *MessageService) NewRecordValidator(ctx context.Context, stream *cosmos.Stream) (cosmos.RecordValidator, error) {
+	schema, err := js.NewSchema(js.NewGoLoader(stream.JSONSchema))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile JSON schema for stream %s: %w", stream.Key(), err)
+	}
+	return &recordValidator{schema: schema}, nil
+}
+
+func (v *recordValidator) ValidateRecord(record *cosmos.Record) error {
+	result, err := v.schema.Validate(js.NewGoLoader(record.Data))
+	if err != nil {
+		return err
+	}
+
+	if !result.Valid() {
+		return cosmos.Errorf(cosmos.EINVALID, strings.TrimSpace(resultErrors(result)))
 	}
 
 	return nil
 }
 
+// resultErrors returns a human readable description of all the JSON Schema validation errors.
+func resultErrors(result *js.Result) string {
+	msg := strings.Builder{}
+	for _, e := range result.Errors() {
+		if !strings.HasPrefix(e.Description(), "Must validate") {
+			msg.WriteString(fmt.Sprintf("%s\n\n", e))
+		}
+	}
+	return msg.String()
+}
+
 func (s *MessageService) MessageToForm(ctx context.Context, message *cosmos.Message, additionalInfo interface{}) *cosmos.Form {
 	switch message.Type {
 
@@ -212,6 +237,8 @@ func (s *MessageService) MessageToForm(ctx context.Context, message *cosmos.Mess
 			field.StreamNamespace = stream.Namespace
 			field.StreamName = stream.Name
 			field.IsStreamSelected = true
+			field.Fields = Map{stream.JSONSchema}.M(js.KEY_PROPERTIES).Keys()
+			field.SelectedFields = append([]string{}, field.Fields...)
 
 			if stream.IsSyncModeAvailable(cosmos.SyncModeFullRefresh) {
 				if contains(supportedDestinationSyncModes, cosmos.DestinationSyncModeOverwrite) {
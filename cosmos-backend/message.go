@@ -124,10 +124,16 @@ type ConnectionStatus struct {
 	Message *string `json:"message,omitempty"`
 }
 
+// RecordValidator validates records against the JSON schema of a single stream.
+type RecordValidator interface {
+	ValidateRecord(record *Record) error
+}
+
 type MessageService interface {
 	CreateMessage(ctx context.Context, raw []byte) (*Message, error)
 	MessageToForm(ctx context.Context, message *Message, additionalInfo interface{}) *Form
 	Validate(ctx context.Context, raw interface{}, message *Message) error
+	NewRecordValidator(ctx context.Context, stream *Stream) (RecordValidator, error)
 }
 
 func (m *Message) String() string {
@@ -135,6 +141,22 @@ func (m *Message) String() string {
 	return string(b)
 }
 
+// StreamKey returns a key which uniquely identifies a stream within a catalog.
+func StreamKey(namespace *string, name string) string {
+	if namespace == nil {
+		return name
+	}
+	return *namespace + "." + name
+}
+
+func (s *Stream) Key() string {
+	return StreamKey(s.Namespace, s.Name)
+}
+
+func (r *Record) StreamKey() string {
+	return StreamKey(r.Namespace, r.Stream)
+}
+
 func (s *Stream) IsSyncModeAvailable(syncMode string) bool {
 	// SyncModeFullRefresh is supported by all sources even if sync.SupportedSyncModes is empty.
 	if syncMode == SyncModeFullRefresh {
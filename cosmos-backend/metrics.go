@@ -0,0 +1,30 @@
+package cosmos
+
+import (
+	"net/http"
+	"time"
+)
+
+// MetricsService records the operational metrics of cosmos and exposes them for scraping.
+type MetricsService interface {
+	// ObserveRun records a run of a sync that finished with the given status.
+	ObserveRun(syncID int, status string, duration time.Duration)
+
+	// ObserveRecords records the records of a stream that were sent to the destination.
+	ObserveRecords(syncID int, stream string, n int)
+
+	// ObserveQueueDepth records the number of runs that are waiting to be started.
+	ObserveQueueDepth(n int)
+
+	// ObserveCommand records a docker command that was run for a connector. err is the error (if any) that it failed with.
+	ObserveCommand(connector, command string, duration time.Duration, err error)
+
+	// ObserveActivity records a temporal activity. err is the error (if any) that it failed with.
+	ObserveActivity(activity string, duration time.Duration, err error)
+
+	// ObserveHTTPRequest records an API request.
+	ObserveHTTPRequest(route, method string, code int, duration time.Duration)
+
+	// MetricsHandler serves the metrics.
+	MetricsHandler() http.Handler
+}
@@ -0,0 +1,49 @@
+package opentelemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+)
+
+// traceParentHeader is the W3C trace context header that identifies the current span.
+const traceParentHeader = "traceparent"
+
+// traceParentCarrier holds a single W3C traceparent.
+type traceParentCarrier struct {
+	traceParent string
+}
+
+func (c *traceParentCarrier) Get(key string) string {
+	if key == traceParentHeader {
+		return c.traceParent
+	}
+	return ""
+}
+
+func (c *traceParentCarrier) Set(key, value string) {
+	if key == traceParentHeader {
+		c.traceParent = value
+	}
+}
+
+func (c *traceParentCarrier) Keys() []string {
+	return []string{traceParentHeader}
+}
+
+// TraceParent returns the W3C traceparent of the current span of ctx, so that it can be
+// stored and the trace continued later. It is empty if there is no span or spans aren't recorded.
+func TraceParent(ctx context.Context) string {
+	carrier := &traceParentCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier.traceParent
+}
+
+// ContextWithTraceParent returns a context whose spans are children of the span with the given
+// W3C traceparent. ctx is returned unchanged if the traceparent is empty or invalid.
+func ContextWithTraceParent(ctx context.Context, traceParent string) context.Context {
+	if traceParent == "" {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, &traceParentCarrier{traceParent: traceParent})
+}
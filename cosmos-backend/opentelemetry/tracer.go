@@ -0,0 +1,99 @@
+package opentelemetry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+// Exporters of spans.
+const (
+	ExporterNone = ""
+	ExporterOTLP = "otlp"
+	ExporterFile = "file"
+)
+
+// TracerProvider exports the spans of the global tracer provider with OTLP over gRPC
+// to a collector or as JSON lines to a file. Spans are not recorded if there is no exporter.
+type TracerProvider struct {
+	serviceName string
+	exporter    string
+	endpoint    string
+	path        string
+
+	file     *os.File
+	provider *sdktrace.TracerProvider
+}
+
+func NewTracerProvider(serviceName, exporter, endpoint, path string) *TracerProvider {
+	return &TracerProvider{
+		serviceName: serviceName,
+		exporter:    exporter,
+		endpoint:    endpoint,
+		path:        path,
+	}
+}
+
+// Open installs the tracer provider as the global tracer provider. W3C trace context
+// headers are propagated irrespective of whether spans are exported.
+func (p *TracerProvider) Open() error {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var exporter sdktrace.SpanExporter
+	switch p.exporter {
+	case ExporterNone:
+		return nil
+	case ExporterOTLP:
+		var err error
+		exporter, err = otlptracegrpc.New(
+			context.Background(),
+			otlptracegrpc.WithEndpoint(p.endpoint),
+			otlptracegrpc.WithInsecure(),
+		)
+		if err != nil {
+			return err
+		}
+	case ExporterFile:
+		var err error
+		if p.file, err = os.OpenFile(p.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644); err != nil {
+			return err
+		}
+		if exporter, err = stdouttrace.New(stdouttrace.WithWriter(p.file)); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown trace exporter %q", p.exporter)
+	}
+
+	p.provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(sdkresource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(p.serviceName))),
+	)
+	otel.SetTracerProvider(p.provider)
+
+	return nil
+}
+
+// Close flushes the spans that haven't been exported yet.
+func (p *TracerProvider) Close() error {
+	if p.provider != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := p.provider.Shutdown(ctx); err != nil {
+			return err
+		}
+	}
+	if p.file != nil {
+		return p.file.Close()
+	}
+	return nil
+}
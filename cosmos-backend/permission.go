@@ -0,0 +1,201 @@
+package cosmos
+
+import (
+	"context"
+	"time"
+)
+
+// Roles of users. Each role includes the privileges of the roles before it.
+const (
+	// Users without a role can only access the syncs and endpoints that they have permissions for.
+	RoleNone = "none"
+
+	// Viewers can see syncs, runs and their artifacts.
+	RoleViewer = "viewer"
+
+	// Operators can also trigger and cancel runs.
+	RoleOperator = "operator"
+
+	// Admins can also create, edit and delete connectors, endpoints and syncs and manage users.
+	RoleAdmin = "admin"
+)
+
+var roleRanks = map[string]int{
+	RoleNone:     0,
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+func IsValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// HasRole returns true if role has at least the privileges of the required role.
+func HasRole(role, required string) bool {
+	return IsValidRole(role) && roleRanks[role] >= roleRanks[required]
+}
+
+// Permission gives a user a role for a single sync or endpoint in addition to the role of the user
+// in the workspace of the sync or endpoint.
+type Permission struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"userID"`
+	Role       string    `json:"role"`
+	SyncID     *int      `json:"syncID"`
+	EndpointID *int      `json:"endpointID"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// Validate performs some basic validation on the permission object during create.
+func (p *Permission) Validate() error {
+	if !IsValidRole(p.Role) || p.Role == RoleNone {
+		return Errorf(EINVALID, "Role must be one of 'viewer', 'operator' or 'admin'")
+	} else if (p.SyncID == nil) == (p.EndpointID == nil) {
+		return Errorf(EINVALID, "Permission must be for either a sync or an endpoint")
+	}
+	return nil
+}
+
+// PermissionFilter represents a permission search filter.
+type PermissionFilter struct {
+	ID         *int `json:"id"`
+	UserID     *int `json:"userID"`
+	SyncID     *int `json:"syncID"`
+	EndpointID *int `json:"endpointID"`
+
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+type PermissionService interface {
+	FindPermissionByID(ctx context.Context, id int) (*Permission, error)
+	FindPermissions(ctx context.Context, filter PermissionFilter) ([]*Permission, int, error)
+	CreatePermission(ctx context.Context, permission *Permission) error
+	DeletePermission(ctx context.Context, id int) error
+}
+
+// Scope is the sync or endpoint that an action is performed on.
+type Scope struct {
+	SyncID     *int
+	EndpointID *int
+}
+
+func (a *App) CreatePermission(ctx context.Context, permission *Permission) error {
+	// Perform basic field validation.
+	if err := permission.Validate(); err != nil {
+		return err
+	}
+
+	// Make sure that the user and the sync or endpoint exist. Permissions only apply
+	// to the members of the workspace that the sync or endpoint belongs to.
+	user, err := a.FindUserByID(ctx, permission.UserID)
+	if err != nil {
+		return err
+	}
+	if _, ok, err := a.WorkspaceRole(ctx, user, workspaceOf(ctx)); err != nil {
+		return err
+	} else if !ok {
+		return Errorf(EINVALID, "The user is not a member of this workspace")
+	}
+	if v := permission.SyncID; v != nil {
+		if _, err := a.FindSyncByID(ctx, *v); err != nil {
+			return err
+		}
+	}
+	if v := permission.EndpointID; v != nil {
+		if _, err := a.FindEndpointByID(ctx, *v); err != nil {
+			return err
+		}
+	}
+
+	return a.DBService.CreatePermission(NewAuditContext(ctx, AuditActionCreate, AuditEntityPermission, nil, permission), permission)
+}
+
+func (a *App) DeletePermission(ctx context.Context, id int) error {
+	permission, err := a.FindPermissionByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return a.DBService.DeletePermission(NewAuditContext(ctx, AuditActionDelete, AuditEntityPermission, permission, nil), id)
+}
+
+// Authorize returns an EFORBIDDEN error unless the authenticated user has the required role, either
+// in the workspace of the request or through a permission for the given scope (if any).
+func (a *App) Authorize(ctx context.Context, required string, scope *Scope) error {
+	user := UserFromContext(ctx)
+	if user == nil {
+		return Errorf(EUNAUTHORIZED, "Authentication required")
+	}
+
+	role, err := a.roleOf(ctx, user)
+	if err != nil {
+		return err
+	}
+	if HasRole(role, required) {
+		return nil
+	}
+
+	if scope != nil && (scope.SyncID != nil || scope.EndpointID != nil) {
+		permissions, _, err := a.FindPermissions(ctx, PermissionFilter{
+			UserID:     &user.ID,
+			SyncID:     scope.SyncID,
+			EndpointID: scope.EndpointID,
+		})
+		if err != nil {
+			return err
+		}
+		for _, p := range permissions {
+			if HasRole(p.Role, required) {
+				return nil
+			}
+		}
+	}
+
+	return Errorf(EFORBIDDEN, "The %s role is required to perform this action", required)
+}
+
+// PermittedScopes lists the syncs and endpoints which the authenticated user has the required role for
+// through permissions. It returns nil if the user has the required role in the workspace of the request.
+func (a *App) PermittedScopes(ctx context.Context, required string) (*PermittedScopes, error) {
+	user := UserFromContext(ctx)
+	if user == nil {
+		return nil, Errorf(EUNAUTHORIZED, "Authentication required")
+	}
+
+	role, err := a.roleOf(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	if HasRole(role, required) {
+		return nil, nil
+	}
+
+	permissions, _, err := a.FindPermissions(ctx, PermissionFilter{UserID: &user.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := &PermittedScopes{SyncIDs: []int{}, EndpointIDs: []int{}}
+	for _, p := range permissions {
+		if !HasRole(p.Role, required) {
+			continue
+		}
+		if p.SyncID != nil {
+			scopes.SyncIDs = append(scopes.SyncIDs, *p.SyncID)
+		}
+		if p.EndpointID != nil {
+			scopes.EndpointIDs = append(scopes.EndpointIDs, *p.EndpointID)
+		}
+	}
+
+	return scopes, nil
+}
+
+// PermittedScopes are the syncs and endpoints that a user is restricted to.
+type PermittedScopes struct {
+	SyncIDs     []int
+	EndpointIDs []int
+}
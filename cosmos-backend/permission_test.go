@@ -0,0 +1,206 @@
+package cosmos
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// testDBService serves the permissions and workspace members that authorization looks up.
+// The other methods of DBService are not implemented.
+type testDBService struct {
+	DBService
+	permissions []*Permission
+	members     []*WorkspaceMember
+}
+
+func (s *testDBService) FindPermissions(ctx context.Context, filter PermissionFilter) ([]*Permission, int, error) {
+	permissions := []*Permission{}
+	for _, p := range s.permissions {
+		if filter.UserID != nil && p.UserID != *filter.UserID {
+			continue
+		}
+		if filter.SyncID != nil && (p.SyncID == nil || *p.SyncID != *filter.SyncID) {
+			continue
+		}
+		if filter.EndpointID != nil && (p.EndpointID == nil || *p.EndpointID != *filter.EndpointID) {
+			continue
+		}
+		permissions = append(permissions, p)
+	}
+	return permissions, len(permissions), nil
+}
+
+func (s *testDBService) FindWorkspaceMembers(ctx context.Context, filter WorkspaceMemberFilter) ([]*WorkspaceMember, int, error) {
+	members := []*WorkspaceMember{}
+	for _, m := range s.members {
+		if filter.WorkspaceID != nil && m.WorkspaceID != *filter.WorkspaceID {
+			continue
+		}
+		if filter.UserID != nil && m.UserID != *filter.UserID {
+			continue
+		}
+		members = append(members, m)
+	}
+	return members, len(members), nil
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestAuthorize(t *testing.T) {
+	const otherWorkspaceID = 2
+
+	viewer := &User{ID: 1, Username: "viewer", Role: RoleViewer}
+	none := &User{ID: 2, Username: "none", Role: RoleNone}
+	admin := &User{ID: 3, Username: "admin", Role: RoleAdmin}
+	member := &User{ID: 4, Username: "member", Role: RoleNone}
+
+	app := &App{DBService: &testDBService{
+		permissions: []*Permission{
+			{ID: 1, UserID: viewer.ID, Role: RoleOperator, SyncID: intPtr(1)},
+			{ID: 2, UserID: none.ID, Role: RoleViewer, SyncID: intPtr(1)},
+			{ID: 3, UserID: none.ID, Role: RoleViewer, EndpointID: intPtr(5)},
+		},
+		members: []*WorkspaceMember{
+			{ID: 1, WorkspaceID: otherWorkspaceID, UserID: member.ID, Role: RoleOperator},
+		},
+	}}
+
+	tests := []struct {
+		name      string
+		user      *User
+		workspace *int
+		required  string
+		scope     *Scope
+		wantCode  string
+	}{
+		{"unauthenticated", nil, nil, RoleViewer, nil, EUNAUTHORIZED},
+		{"viewer can view", viewer, nil, RoleViewer, nil, ""},
+		{"viewer can't operate", viewer, nil, RoleOperator, nil, EFORBIDDEN},
+		{"viewer can operate permitted sync", viewer, nil, RoleOperator, &Scope{SyncID: intPtr(1)}, ""},
+		{"viewer can't operate other sync", viewer, nil, RoleOperator, &Scope{SyncID: intPtr(2)}, EFORBIDDEN},
+		{"viewer can't edit permitted sync", viewer, nil, RoleAdmin, &Scope{SyncID: intPtr(1)}, EFORBIDDEN},
+		{"none can view permitted sync", none, nil, RoleViewer, &Scope{SyncID: intPtr(1)}, ""},
+		{"none can't view other sync", none, nil, RoleViewer, &Scope{SyncID: intPtr(2)}, EFORBIDDEN},
+		{"none can view permitted endpoint", none, nil, RoleViewer, &Scope{EndpointID: intPtr(5)}, ""},
+		{"none can't view without scope", none, nil, RoleViewer, nil, EFORBIDDEN},
+		{"viewer can view default workspace", viewer, intPtr(DefaultWorkspaceID), RoleViewer, nil, ""},
+		{"non-member can't view foreign workspace", viewer, intPtr(otherWorkspaceID), RoleViewer, nil, EFORBIDDEN},
+		{"member can operate in workspace", member, intPtr(otherWorkspaceID), RoleOperator, nil, ""},
+		{"member can't operate in default workspace", member, intPtr(DefaultWorkspaceID), RoleOperator, nil, EFORBIDDEN},
+		{"admin can edit foreign workspace", admin, intPtr(otherWorkspaceID), RoleAdmin, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.user != nil {
+				ctx = NewAuthContext(ctx, tt.user, nil)
+			}
+			if tt.workspace != nil {
+				ctx = NewWorkspaceContext(ctx, *tt.workspace)
+			}
+
+			err := app.Authorize(ctx, tt.required, tt.scope)
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+			} else if code := ErrorCode(err); code != tt.wantCode {
+				t.Fatalf("unexpected error code: %q, want: %q", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestPermittedScopes(t *testing.T) {
+	const otherWorkspaceID = 2
+
+	viewer := &User{ID: 1, Username: "viewer", Role: RoleViewer}
+	none := &User{ID: 2, Username: "none", Role: RoleNone}
+
+	app := &App{DBService: &testDBService{
+		permissions: []*Permission{
+			{ID: 1, UserID: viewer.ID, Role: RoleOperator, SyncID: intPtr(1)},
+			{ID: 2, UserID: viewer.ID, Role: RoleViewer, SyncID: intPtr(2)},
+			{ID: 3, UserID: none.ID, Role: RoleViewer, EndpointID: intPtr(5)},
+		},
+	}}
+
+	tests := []struct {
+		name      string
+		user      *User
+		workspace *int
+		required  string
+		want      *PermittedScopes
+	}{
+		{"viewer views everything", viewer, nil, RoleViewer, nil},
+		{"viewer operates permitted syncs only", viewer, nil, RoleOperator, &PermittedScopes{SyncIDs: []int{1}, EndpointIDs: []int{}}},
+		{"viewer edits nothing", viewer, nil, RoleAdmin, &PermittedScopes{SyncIDs: []int{}, EndpointIDs: []int{}}},
+		{"none views permitted endpoints only", none, nil, RoleViewer, &PermittedScopes{SyncIDs: []int{}, EndpointIDs: []int{5}}},
+		{"non-member views permitted syncs only in foreign workspace", viewer, intPtr(otherWorkspaceID), RoleViewer, &PermittedScopes{SyncIDs: []int{1, 2}, EndpointIDs: []int{}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewAuthContext(context.Background(), tt.user, nil)
+			if tt.workspace != nil {
+				ctx = NewWorkspaceContext(ctx, *tt.workspace)
+			}
+
+			scopes, err := app.PermittedScopes(ctx, tt.required)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(scopes, tt.want) {
+				t.Fatalf("unexpected scopes: %+v, want: %+v", scopes, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoleOf(t *testing.T) {
+	const otherWorkspaceID = 2
+
+	viewer := &User{ID: 1, Username: "viewer", Role: RoleViewer}
+	admin := &User{ID: 2, Username: "admin", Role: RoleAdmin}
+	member := &User{ID: 3, Username: "member", Role: RoleViewer}
+
+	app := &App{DBService: &testDBService{
+		members: []*WorkspaceMember{
+			{ID: 1, WorkspaceID: otherWorkspaceID, UserID: member.ID, Role: RoleAdmin},
+		},
+	}}
+
+	tests := []struct {
+		name      string
+		user      *User
+		workspace *int
+		want      string
+	}{
+		{"user role without workspace", viewer, nil, RoleViewer},
+		{"user role in default workspace", viewer, intPtr(DefaultWorkspaceID), RoleViewer},
+		{"non-member in foreign workspace", viewer, intPtr(otherWorkspaceID), RoleNone},
+		{"member role in workspace", member, intPtr(otherWorkspaceID), RoleAdmin},
+		{"admin in foreign workspace", admin, intPtr(otherWorkspaceID), RoleAdmin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.workspace != nil {
+				ctx = NewWorkspaceContext(ctx, *tt.workspace)
+			}
+
+			role, err := app.roleOf(ctx, tt.user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if role != tt.want {
+				t.Fatalf("unexpected role: %s, want: %s", role, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package cosmos
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/iancoleman/orderedmap"
+)
+
+// Field policy actions.
+const (
+	FieldPolicyDrop     = "drop"
+	FieldPolicyHash     = "hash"
+	FieldPolicyRedact   = "redact"
+	FieldPolicyTruncate = "truncate"
+)
+
+// RedactedValue replaces the values of redacted fields.
+const RedactedValue = "**********"
+
+// FieldPolicy describes how a single field of a stream must be masked before
+// the record reaches the destination.
+type FieldPolicy struct {
+	Path   []string `json:"path"`
+	Action string   `json:"action"`
+	Salt   string   `json:"salt,omitempty"`
+	Length int      `json:"length,omitempty"`
+}
+
+func (p *FieldPolicy) Validate() error {
+	if len(p.Path) == 0 {
+		return fmt.Errorf("Field policy requires a field path")
+	}
+
+	switch p.Action {
+	case FieldPolicyDrop, FieldPolicyRedact:
+	case FieldPolicyHash:
+		if p.Salt == "" {
+			return fmt.Errorf("Hash policy on field %v requires a non-empty salt", p.Path)
+		}
+	case FieldPolicyTruncate:
+		if p.Length <= 0 {
+			return fmt.Errorf("Truncate policy on field %v requires a length greater than 0", p.Path)
+		}
+	default:
+		return fmt.Errorf("Invalid field policy action: %s", p.Action)
+	}
+
+	return nil
+}
+
+// Apply masks the field in the record data according to the policy.
+// Records which don't contain the field are left untouched.
+func (p *FieldPolicy) Apply(data map[string]interface{}) {
+	parent := data
+	for _, key := range p.Path[:len(p.Path)-1] {
+		child, ok := parent[key].(map[string]interface{})
+		if !ok {
+			return
+		}
+		parent = child
+	}
+
+	key := p.Path[len(p.Path)-1]
+	value, ok := parent[key]
+	if !ok {
+		return
+	}
+
+	switch p.Action {
+	case FieldPolicyDrop:
+		delete(parent, key)
+	case FieldPolicyHash:
+		if value != nil {
+			sum := sha256.Sum256([]byte(p.Salt + stringify(value)))
+			parent[key] = hex.EncodeToString(sum[:])
+		}
+	case FieldPolicyRedact:
+		if value != nil {
+			parent[key] = RedactedValue
+		}
+	case FieldPolicyTruncate:
+		if s, ok := value.(string); ok && len([]rune(s)) > p.Length {
+			parent[key] = string([]rune(s)[:p.Length])
+		}
+	}
+}
+
+// ApplyToSchema modifies the JSON schema of a stream so that it describes the
+// field after the policy has been applied.
+func (p *FieldPolicy) ApplyToSchema(schema *orderedmap.OrderedMap) {
+	updateSchemaProperty(schema, p.Path, func(property orderedmap.OrderedMap) *orderedmap.OrderedMap {
+		switch p.Action {
+		case FieldPolicyDrop:
+			return nil
+		case FieldPolicyHash, FieldPolicyRedact:
+			// Hashed and redacted values are always strings.
+			m := orderedmap.New()
+			m.Set("type", []interface{}{"null", "string"})
+			return m
+		}
+		return &property
+	})
+}
+
+// Masked returns a copy of the policy which is safe to display to the user.
+func (p *FieldPolicy) Masked() *FieldPolicy {
+	masked := *p
+	if masked.Salt != "" {
+		masked.Salt = RedactedValue
+	}
+	return &masked
+}
+
+// MaskedFieldPolicies returns a copy of the catalog form in which the field policies are masked.
+func (f *Form) MaskedFieldPolicies() *Form {
+	form := *f
+	form.Catalog = make([]*FormFieldCatalog, len(f.Catalog))
+	for i, field := range f.Catalog {
+		field := *field
+		if field.FieldPolicies != nil {
+			policies := make([]*FieldPolicy, len(field.FieldPolicies))
+			for j, policy := range field.FieldPolicies {
+				policies[j] = policy.Masked()
+			}
+			field.FieldPolicies = policies
+		}
+		form.Catalog[i] = &field
+	}
+	return &form
+}
+
+// FieldPolicies returns the field policies of all the selected streams in a catalog form keyed by stream.
+func (f *Form) FieldPolicies() map[string][]*FieldPolicy {
+	result := map[string][]*FieldPolicy{}
+	for _, field := range f.Catalog {
+		if field.IsStreamSelected && len(field.FieldPolicies) != 0 {
+			result[field.Stream.Key()] = field.FieldPolicies
+		}
+	}
+	return result
+}
+
+// updateSchemaProperty replaces the schema of the property at the given path in a JSON schema with
+// the schema returned by fn. The property is removed from the JSON schema if fn returns nil.
+func updateSchemaProperty(schema *orderedmap.OrderedMap, path []string, fn func(property orderedmap.OrderedMap) *orderedmap.OrderedMap) {
+	if len(path) == 0 {
+		return
+	}
+
+	v, _ := schema.Get("properties")
+	properties, ok := v.(orderedmap.OrderedMap)
+	if !ok {
+		return
+	}
+	v, _ = properties.Get(path[0])
+	property, ok := v.(orderedmap.OrderedMap)
+	if !ok {
+		return
+	}
+
+	if len(path) > 1 {
+		updateSchemaProperty(&property, path[1:], fn)
+		properties.Set(path[0], property)
+	} else if updated := fn(property); updated != nil {
+		properties.Set(path[0], *updated)
+	} else {
+		properties.Delete(path[0])
+
+		// A property that no longer exists cannot be required.
+		v, _ := schema.Get("required")
+		if required, ok := v.([]interface{}); ok {
+			remaining := []interface{}{}
+			for _, r := range required {
+				if r != path[0] {
+					remaining = append(remaining, r)
+				}
+			}
+			schema.Set("required", remaining)
+		}
+	}
+
+	schema.Set("properties", properties)
+}
+
+// stringify returns the string representation of a JSON value.
+func stringify(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(value)
+	return string(b)
+}
@@ -0,0 +1,240 @@
+package cosmos
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/iancoleman/orderedmap"
+)
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestFieldPolicyApply(t *testing.T) {
+	// newRecord returns a new record every time since the policies modify the record in place.
+	newRecord := func() map[string]interface{} {
+		return map[string]interface{}{
+			"id": 1.0,
+			"contact": map[string]interface{}{
+				"email": "jane@example.com",
+				"phone": nil,
+				"address": map[string]interface{}{
+					"street": "Bahnhofstraße 1",
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		policy FieldPolicy
+		want   map[string]interface{}
+	}{
+		{
+			name:   "drop nested field",
+			policy: FieldPolicy{Path: []string{"contact", "email"}, Action: FieldPolicyDrop},
+			want: map[string]interface{}{
+				"id": 1.0,
+				"contact": map[string]interface{}{
+					"phone":   nil,
+					"address": map[string]interface{}{"street": "Bahnhofstraße 1"},
+				},
+			},
+		},
+		{
+			name:   "hash nested field with salt",
+			policy: FieldPolicy{Path: []string{"contact", "email"}, Action: FieldPolicyHash, Salt: "pepper"},
+			want: map[string]interface{}{
+				"id": 1.0,
+				"contact": map[string]interface{}{
+					"email":   sha256Hex("pepperjane@example.com"),
+					"phone":   nil,
+					"address": map[string]interface{}{"street": "Bahnhofstraße 1"},
+				},
+			},
+		},
+		{
+			name:   "hash non-string field",
+			policy: FieldPolicy{Path: []string{"id"}, Action: FieldPolicyHash, Salt: "pepper"},
+			want: map[string]interface{}{
+				"id": sha256Hex("pepper1"),
+				"contact": map[string]interface{}{
+					"email":   "jane@example.com",
+					"phone":   nil,
+					"address": map[string]interface{}{"street": "Bahnhofstraße 1"},
+				},
+			},
+		},
+		{
+			name:   "redact nested field",
+			policy: FieldPolicy{Path: []string{"contact", "address", "street"}, Action: FieldPolicyRedact},
+			want: map[string]interface{}{
+				"id": 1.0,
+				"contact": map[string]interface{}{
+					"email":   "jane@example.com",
+					"phone":   nil,
+					"address": map[string]interface{}{"street": RedactedValue},
+				},
+			},
+		},
+		{
+			name:   "redact null field",
+			policy: FieldPolicy{Path: []string{"contact", "phone"}, Action: FieldPolicyRedact},
+			want:   newRecord(),
+		},
+		{
+			name:   "truncate nested field by runes",
+			policy: FieldPolicy{Path: []string{"contact", "address", "street"}, Action: FieldPolicyTruncate, Length: 10},
+			want: map[string]interface{}{
+				"id": 1.0,
+				"contact": map[string]interface{}{
+					"email":   "jane@example.com",
+					"phone":   nil,
+					"address": map[string]interface{}{"street": "Bahnhofstr"},
+				},
+			},
+		},
+		{
+			name:   "truncate short field",
+			policy: FieldPolicy{Path: []string{"contact", "email"}, Action: FieldPolicyTruncate, Length: 100},
+			want:   newRecord(),
+		},
+		{
+			name:   "missing field",
+			policy: FieldPolicy{Path: []string{"contact", "fax"}, Action: FieldPolicyDrop},
+			want:   newRecord(),
+		},
+		{
+			name:   "path through non-object",
+			policy: FieldPolicy{Path: []string{"id", "value"}, Action: FieldPolicyRedact},
+			want:   newRecord(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(); err != nil {
+				t.Fatalf("invalid policy: %s", err)
+			}
+			record := newRecord()
+			tt.policy.Apply(record)
+			if !reflect.DeepEqual(record, tt.want) {
+				t.Fatalf("unexpected record: %v, want: %v", record, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldPolicyApplyToSchema(t *testing.T) {
+	const schema = `{
+		"type": "object",
+		"required": ["id", "contact"],
+		"properties": {
+			"id": {"type": "integer"},
+			"contact": {
+				"type": "object",
+				"required": ["email", "phone"],
+				"properties": {
+					"email": {"type": "string", "format": "email"},
+					"phone": {"type": "string"}
+				}
+			}
+		}
+	}`
+
+	tests := []struct {
+		name   string
+		policy FieldPolicy
+		want   string
+	}{
+		{
+			name:   "drop top-level field",
+			policy: FieldPolicy{Path: []string{"id"}, Action: FieldPolicyDrop},
+			want: `{"type":"object","required":["contact"],"properties":{` +
+				`"contact":{"type":"object","required":["email","phone"],"properties":{"email":{"type":"string","format":"email"},"phone":{"type":"string"}}}}}`,
+		},
+		{
+			name:   "drop nested field",
+			policy: FieldPolicy{Path: []string{"contact", "email"}, Action: FieldPolicyDrop},
+			want: `{"type":"object","required":["id","contact"],"properties":{"id":{"type":"integer"},` +
+				`"contact":{"type":"object","required":["phone"],"properties":{"phone":{"type":"string"}}}}}`,
+		},
+		{
+			name:   "hash nested field",
+			policy: FieldPolicy{Path: []string{"contact", "email"}, Action: FieldPolicyHash, Salt: "pepper"},
+			want: `{"type":"object","required":["id","contact"],"properties":{"id":{"type":"integer"},` +
+				`"contact":{"type":"object","required":["email","phone"],"properties":{"email":{"type":["null","string"]},"phone":{"type":"string"}}}}}`,
+		},
+		{
+			name:   "redact non-string field",
+			policy: FieldPolicy{Path: []string{"id"}, Action: FieldPolicyRedact},
+			want: `{"type":"object","required":["id","contact"],"properties":{"id":{"type":["null","string"]},` +
+				`"contact":{"type":"object","required":["email","phone"],"properties":{"email":{"type":"string","format":"email"},"phone":{"type":"string"}}}}}`,
+		},
+		{
+			name:   "truncate nested field",
+			policy: FieldPolicy{Path: []string{"contact", "phone"}, Action: FieldPolicyTruncate, Length: 3},
+			want: `{"type":"object","required":["id","contact"],"properties":{"id":{"type":"integer"},` +
+				`"contact":{"type":"object","required":["email","phone"],"properties":{"email":{"type":"string","format":"email"},"phone":{"type":"string"}}}}}`,
+		},
+		{
+			name:   "missing field",
+			policy: FieldPolicy{Path: []string{"contact", "fax"}, Action: FieldPolicyDrop},
+			want: `{"type":"object","required":["id","contact"],"properties":{"id":{"type":"integer"},` +
+				`"contact":{"type":"object","required":["email","phone"],"properties":{"email":{"type":"string","format":"email"},"phone":{"type":"string"}}}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := orderedmap.New()
+			if err := s.UnmarshalJSON([]byte(schema)); err != nil {
+				t.Fatal(err)
+			}
+			tt.policy.ApplyToSchema(s)
+			got, err := s.MarshalJSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The top level of ordered maps is marshalled with line breaks.
+			if got := strings.ReplaceAll(string(got), "\n", ""); got != tt.want {
+				t.Fatalf("unexpected schema: %s, want: %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskedFieldPolicies(t *testing.T) {
+	form := &Form{Catalog: []*FormFieldCatalog{
+		{
+			IsStreamSelected: true,
+			FieldPolicies: []*FieldPolicy{
+				{Path: []string{"email"}, Action: FieldPolicyHash, Salt: "pepper"},
+				{Path: []string{"phone"}, Action: FieldPolicyRedact},
+			},
+		},
+		{IsStreamSelected: false},
+	}}
+
+	masked := form.MaskedFieldPolicies()
+
+	if got := masked.Catalog[0].FieldPolicies[0].Salt; got != RedactedValue {
+		t.Fatalf("salt isn't masked: %s", got)
+	}
+	if got := masked.Catalog[0].FieldPolicies[1].Salt; got != "" {
+		t.Fatalf("empty salt is masked: %s", got)
+	}
+	if masked.Catalog[1].FieldPolicies != nil {
+		t.Fatalf("unexpected field policies: %v", masked.Catalog[1].FieldPolicies)
+	}
+
+	// The original form must keep the salt, since it's needed to hash the values.
+	if got := form.Catalog[0].FieldPolicies[0].Salt; got != "pepper" {
+		t.Fatalf("salt of the original form is modified: %s", got)
+	}
+}
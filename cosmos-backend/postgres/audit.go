@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"context"
+	"cosmos"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+func (s *DBService) FindAuditEntries(ctx context.Context, filter cosmos.AuditFilter) ([]*cosmos.AuditEntry, int, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback(ctx)
+	return findAuditEntries(ctx, tx, filter)
+}
+
+func (s *DBService) CreateAuditEntry(ctx context.Context, entry *cosmos.AuditEntry) error {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := createAuditEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func findAuditEntries(ctx context.Context, tx *Tx, filter cosmos.AuditFilter) ([]*cosmos.AuditEntry, int, error) {
+	// Build the WHERE clause.
+	where, args, i := []string{"1 = 1"}, []interface{}{}, 1
+	if v := filter.UserID; v != nil {
+		where, args = append(where, fmt.Sprintf("user_id = $%d", i)), append(args, *v)
+		i++
+	}
+	if v := filter.Action; v != nil {
+		where, args = append(where, fmt.Sprintf("action = $%d", i)), append(args, *v)
+		i++
+	}
+	if v := filter.EntityType; v != nil {
+		where, args = append(where, fmt.Sprintf("entity_type = $%d", i)), append(args, *v)
+		i++
+	}
+	if v := filter.EntityID; v != nil {
+		where, args = append(where, fmt.Sprintf("entity_id = $%d", i)), append(args, *v)
+		i++
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT
+			id,
+			user_id,
+			username,
+			action,
+			entity_type,
+			entity_id,
+			changes,
+			created_at,
+			COUNT(*) OVER()
+		FROM audit_log
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY id DESC
+		`+FormatLimitOffset(filter.Limit, filter.Offset),
+		args...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	// Iterate over the returned rows and deserialize into cosmos.AuditEntry objects.
+	entries := []*cosmos.AuditEntry{}
+	totalEntries := 0
+	for rows.Next() {
+		var entry cosmos.AuditEntry
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.UserID,
+			&entry.Username,
+			&entry.Action,
+			&entry.EntityType,
+			&entry.EntityID,
+			(*AuditChanges)(&entry.Changes),
+			(*NullTime)(&entry.CreatedAt),
+			&totalEntries,
+		); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, &entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return entries, totalEntries, nil
+}
+
+func createAuditEntry(ctx context.Context, tx *Tx, entry *cosmos.AuditEntry) error {
+	// Set timestamps to current time.
+	entry.CreatedAt = tx.now
+
+	// Insert audit entry into database.
+	err := tx.QueryRow(ctx, `
+		INSERT INTO audit_log (
+			user_id,
+			username,
+			action,
+			entity_type,
+			entity_id,
+			changes,
+			created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`,
+		entry.UserID,
+		entry.Username,
+		entry.Action,
+		entry.EntityType,
+		entry.EntityID,
+		(*AuditChanges)(&entry.Changes),
+		(*NullTime)(&entry.CreatedAt),
+	).Scan(&entry.ID)
+
+	if err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
+
+// recordAudit records the audit entry of a mutation in the transaction of the mutation if it is audited.
+func recordAudit(ctx context.Context, tx *Tx, entityID int) error {
+	audit, ok := cosmos.AuditFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	return createAuditEntry(ctx, tx, audit.Entry(entityID))
+}
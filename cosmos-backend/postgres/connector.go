@@ -38,6 +38,10 @@ func (s *DBService) CreateConnector(ctx context.Context, connector *cosmos.Conne
 		return err
 	}
 
+	if err := recordAudit(ctx, tx, connector.ID); err != nil {
+		return err
+	}
+
 	return tx.Commit(ctx)
 }
 
@@ -52,6 +56,10 @@ func (s *DBService) UpdateConnector(ctx context.Context, id int, connector *cosm
 		return err
 	}
 
+	if err := recordAudit(ctx, tx, id); err != nil {
+		return err
+	}
+
 	return tx.Commit(ctx)
 }
 
@@ -66,6 +74,10 @@ func (s *DBService) DeleteConnector(ctx context.Context, id int) error {
 		return err
 	}
 
+	if err := recordAudit(ctx, tx, id); err != nil {
+		return err
+	}
+
 	return tx.Commit(ctx)
 }
 
@@ -95,9 +107,16 @@ func findConnectors(ctx context.Context, tx *Tx, filter cosmos.ConnectorFilter)
 		i++
 	}
 
+	// Restrict to the connectors that are visible in the workspace of the request.
+	if v, ok := cosmos.WorkspaceFromContext(ctx); ok {
+		where, args = append(where, fmt.Sprintf("(workspace_id IS NULL OR workspace_id = $%d)", i)), append(args, v)
+		i++
+	}
+
 	rows, err := tx.Query(ctx, `
 		SELECT
 			id,
+			workspace_id,
 			name,
 			type,
 			docker_image_name,
@@ -125,6 +144,7 @@ func findConnectors(ctx context.Context, tx *Tx, filter cosmos.ConnectorFilter)
 		var connector cosmos.Connector
 		if err := rows.Scan(
 			&connector.ID,
+			&connector.WorkspaceID,
 			&connector.Name,
 			&connector.Type,
 			&connector.DockerImageName,
@@ -154,6 +174,7 @@ func createConnector(ctx context.Context, tx *Tx, connector *cosmos.Connector) e
 	// Insert connector into database.
 	err := tx.QueryRow(ctx, `
 		INSERT INTO connectors (
+			workspace_id,
 			name,
 			type,
 			docker_image_name,
@@ -163,9 +184,10 @@ func createConnector(ctx context.Context, tx *Tx, connector *cosmos.Connector) e
 			created_at,
 			updated_at
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id
 	`,
+		connector.WorkspaceID,
 		connector.Name,
 		connector.Type,
 		connector.DockerImageName,
@@ -6,6 +6,10 @@ var _ cosmos.DBService = (*DBService)(nil)
 
 type DBService struct {
 	db *DB
+
+	// Cipher encrypts the values of secret fields of endpoint configs at rest.
+	// They are stored in plaintext if it is nil.
+	Cipher *cosmos.Cipher
 }
 
 func NewDBService(db *DB) *DBService {
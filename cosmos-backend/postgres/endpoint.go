@@ -35,10 +35,18 @@ func (s *DBService) CreateEndpoint(ctx context.Context, endpoint *cosmos.Endpoin
 	}
 	defer tx.Rollback(ctx)
 
+	if _, err := s.encryptSecrets(&endpoint.Config); err != nil {
+		return err
+	}
+
 	if err := createEndpoint(ctx, tx, endpoint); err != nil {
 		return err
 	}
 
+	if err := recordAudit(ctx, tx, endpoint.ID); err != nil {
+		return err
+	}
+
 	return tx.Commit(ctx)
 }
 
@@ -49,10 +57,18 @@ func (s *DBService) UpdateEndpoint(ctx context.Context, id int, endpoint *cosmos
 	}
 	defer tx.Rollback(ctx)
 
+	if _, err := s.encryptSecrets(&endpoint.Config); err != nil {
+		return err
+	}
+
 	if err := updateEndpoint(ctx, tx, id, endpoint); err != nil {
 		return err
 	}
 
+	if err := recordAudit(ctx, tx, id); err != nil {
+		return err
+	}
+
 	return tx.Commit(ctx)
 }
 
@@ -67,9 +83,94 @@ func (s *DBService) DeleteEndpoint(ctx context.Context, id int) error {
 		return err
 	}
 
+	if err := recordAudit(ctx, tx, id); err != nil {
+		return err
+	}
+
 	return tx.Commit(ctx)
 }
 
+// EncryptSecrets encrypts the secrets of all endpoints which are either stored in plaintext
+// or have been encrypted with a key other than the primary key, e.g, after the keys have been rotated.
+// It returns the number of endpoints which have been updated.
+func (s *DBService) EncryptSecrets(ctx context.Context) (int, error) {
+	if s.Cipher == nil {
+		return 0, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	endpoints, _, err := findEndpoints(ctx, tx, cosmos.EndpointFilter{})
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for _, endpoint := range endpoints {
+		changed, err := s.encryptSecrets(&endpoint.Config)
+		if err != nil {
+			return 0, fmt.Errorf("endpoint %d: %w", endpoint.ID, err)
+		} else if !changed {
+			continue
+		}
+
+		// Only the config is updated since nothing about the endpoint has changed.
+		if _, err := tx.Exec(ctx, `
+			UPDATE endpoints
+			SET config = $1
+			WHERE id = $2
+		`,
+			(*Form)(&endpoint.Config),
+			endpoint.ID,
+		); err != nil {
+			return 0, err
+		}
+		updated++
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return updated, nil
+}
+
+// encryptSecrets encrypts the values of the secret fields of a config form with the primary key.
+// Values which have already been encrypted with the primary key are left as is. So are references
+// to external secrets, since they are not secrets themselves.
+// It returns true if any value has been encrypted.
+func (s *DBService) encryptSecrets(form *cosmos.Form) (bool, error) {
+	if s.Cipher == nil {
+		return false, nil
+	}
+
+	changed := false
+	for _, field := range form.Spec {
+		if !field.Secret || field.Value == nil || s.Cipher.IsPrimary(field.Value) {
+			continue
+		}
+		if _, _, ok := cosmos.ParseSecretRef(field.Value); ok {
+			continue
+		}
+
+		// Values encrypted with an older key are decrypted first so that they aren't encrypted twice.
+		value, err := s.Cipher.OpenValue(field.Value)
+		if err != nil {
+			return false, err
+		}
+		if field.Value, err = s.Cipher.SealValue(value); err != nil {
+			return false, err
+		}
+		changed = true
+	}
+
+	return changed, nil
+}
+
 func findEndpointByID(ctx context.Context, tx *Tx, id int) (*cosmos.Endpoint, error) {
 	endpoints, totalEndpoints, err := findEndpoints(ctx, tx, cosmos.EndpointFilter{ID: &id})
 	if err != nil {
@@ -95,10 +196,21 @@ func findEndpoints(ctx context.Context, tx *Tx, filter cosmos.EndpointFilter) ([
 		where, args = append(where, fmt.Sprintf("type = $%d", i)), append(args, *v)
 		i++
 	}
+	if v := filter.IDs; v != nil {
+		where, args = append(where, fmt.Sprintf("id = ANY($%d)", i)), append(args, v)
+		i++
+	}
+
+	// Restrict to the workspace of the request.
+	if v, ok := cosmos.WorkspaceFromContext(ctx); ok {
+		where, args = append(where, fmt.Sprintf("workspace_id = $%d", i)), append(args, v)
+		i++
+	}
 
 	rows, err := tx.Query(ctx, `
 		SELECT
 			id,
+			workspace_id,
 			name,
 			type,
 			connector_id,
@@ -127,6 +239,7 @@ func findEndpoints(ctx context.Context, tx *Tx, filter cosmos.EndpointFilter) ([
 
 		if err := rows.Scan(
 			&endpoint.ID,
+			&endpoint.WorkspaceID,
 			&endpoint.Name,
 			&endpoint.Type,
 			&endpoint.ConnectorID,
@@ -165,6 +278,7 @@ func createEndpoint(ctx context.Context, tx *Tx, endpoint *cosmos.Endpoint) erro
 	// Insert endpoint into database.
 	err := tx.QueryRow(ctx, `
 		INSERT INTO endpoints (
+			workspace_id,
 			name,
 			type,
 			connector_id,
@@ -174,9 +288,10 @@ func createEndpoint(ctx context.Context, tx *Tx, endpoint *cosmos.Endpoint) erro
 			created_at,
 			updated_at
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id
 	`,
+		endpoint.WorkspaceID,
 		endpoint.Name,
 		endpoint.Type,
 		endpoint.ConnectorID,
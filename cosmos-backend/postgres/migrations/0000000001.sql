@@ -0,0 +1 @@
+ALTER TABLE syncs ADD COLUMN validation_mode TEXT NOT NULL DEFAULT 'off';
@@ -0,0 +1,4 @@
+ALTER TABLE syncs ADD COLUMN schema_change_policy TEXT NOT NULL DEFAULT 'ignore';
+ALTER TABLE syncs ADD COLUMN schema_changes TEXT NOT NULL DEFAULT '[]';
+
+CREATE INDEX syncs_source_endpoint_id_idx ON syncs (source_endpoint_id);
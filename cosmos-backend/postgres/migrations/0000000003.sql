@@ -0,0 +1 @@
+ALTER TABLE runs ADD COLUMN artifacts_purged BOOLEAN NOT NULL DEFAULT FALSE;
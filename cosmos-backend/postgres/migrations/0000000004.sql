@@ -0,0 +1,24 @@
+CREATE TABLE users (
+    id                   SERIAL PRIMARY KEY,
+    username             TEXT NOT NULL,
+    password_hash        TEXT NOT NULL,
+    created_at           TEXT NOT NULL,
+    updated_at           TEXT NOT NULL,
+
+    UNIQUE(username)
+);
+
+CREATE TABLE tokens (
+    id                   SERIAL PRIMARY KEY,
+    name                 TEXT NOT NULL,
+    user_id              INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    hash                 TEXT NOT NULL,
+    session              BOOLEAN NOT NULL,
+    expires_at           TEXT,
+    last_used_at         TEXT,
+    created_at           TEXT NOT NULL,
+
+    UNIQUE(hash)
+);
+
+CREATE INDEX tokens_user_id_idx ON tokens (user_id);
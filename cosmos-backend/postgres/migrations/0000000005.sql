@@ -0,0 +1,16 @@
+-- Users created before roles were introduced had full access, so they become admins.
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'admin';
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'viewer';
+
+CREATE TABLE permissions (
+    id                   SERIAL PRIMARY KEY,
+    user_id              INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role                 TEXT NOT NULL,
+    sync_id              INT REFERENCES syncs (id) ON DELETE CASCADE,
+    endpoint_id          INT REFERENCES endpoints (id) ON DELETE CASCADE,
+    created_at           TEXT NOT NULL,
+
+    CHECK ((sync_id IS NULL) <> (endpoint_id IS NULL))
+);
+
+CREATE INDEX permissions_user_id_idx ON permissions (user_id);
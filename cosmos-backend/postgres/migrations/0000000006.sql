@@ -0,0 +1,13 @@
+CREATE TABLE audit_log (
+    id                   SERIAL PRIMARY KEY,
+    user_id              INT NOT NULL,
+    username             TEXT NOT NULL,
+    action               TEXT NOT NULL,
+    entity_type          TEXT NOT NULL,
+    entity_id            INT NOT NULL,
+    changes              TEXT NOT NULL,
+    created_at           TEXT NOT NULL
+);
+
+CREATE INDEX audit_log_entity_idx ON audit_log (entity_type, entity_id);
+CREATE INDEX audit_log_user_id_idx ON audit_log (user_id);
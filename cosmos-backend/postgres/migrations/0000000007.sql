@@ -0,0 +1,34 @@
+CREATE TABLE workspaces (
+    id                   SERIAL PRIMARY KEY,
+    name                 TEXT NOT NULL,
+    created_at           TEXT NOT NULL,
+    updated_at           TEXT NOT NULL,
+
+    UNIQUE(name)
+);
+
+-- Everything created before workspaces were introduced belongs to the default workspace (ID 1).
+INSERT INTO workspaces (name, created_at, updated_at) VALUES (
+    'Default',
+    TO_CHAR(CURRENT_TIMESTAMP, 'YYYY-MM-DD"T"HH24:MI:SS"Z"'),
+    TO_CHAR(CURRENT_TIMESTAMP, 'YYYY-MM-DD"T"HH24:MI:SS"Z"')
+);
+
+-- Connectors without a workspace are shared by all workspaces.
+ALTER TABLE connectors ADD COLUMN workspace_id INT REFERENCES workspaces (id);
+ALTER TABLE connectors DROP CONSTRAINT connectors_name_type_key;
+ALTER TABLE connectors DROP CONSTRAINT connectors_docker_image_name_docker_image_tag_key;
+ALTER TABLE connectors ADD CONSTRAINT connectors_workspace_id_name_type_key UNIQUE (workspace_id, name, type);
+ALTER TABLE connectors ADD CONSTRAINT connectors_workspace_id_docker_image_name_docker_image_tag_key UNIQUE (workspace_id, docker_image_name, docker_image_tag);
+CREATE UNIQUE INDEX connectors_shared_name_type_idx ON connectors (name, type) WHERE workspace_id IS NULL;
+CREATE UNIQUE INDEX connectors_shared_docker_image_name_docker_image_tag_idx ON connectors (docker_image_name, docker_image_tag) WHERE workspace_id IS NULL;
+
+ALTER TABLE endpoints ADD COLUMN workspace_id INT NOT NULL DEFAULT 1 REFERENCES workspaces (id);
+ALTER TABLE endpoints ALTER COLUMN workspace_id DROP DEFAULT;
+ALTER TABLE endpoints DROP CONSTRAINT endpoints_name_type_key;
+ALTER TABLE endpoints ADD CONSTRAINT endpoints_workspace_id_name_type_key UNIQUE (workspace_id, name, type);
+
+ALTER TABLE syncs ADD COLUMN workspace_id INT NOT NULL DEFAULT 1 REFERENCES workspaces (id);
+ALTER TABLE syncs ALTER COLUMN workspace_id DROP DEFAULT;
+ALTER TABLE syncs DROP CONSTRAINT syncs_name_key;
+ALTER TABLE syncs ADD CONSTRAINT syncs_workspace_id_name_key UNIQUE (workspace_id, name);
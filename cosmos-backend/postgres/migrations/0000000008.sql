@@ -0,0 +1,13 @@
+-- Members of a workspace have a role within it. All users are members of the default workspace
+-- with the role of the user, so only the other workspaces have members listed.
+CREATE TABLE workspace_members (
+    id                   SERIAL PRIMARY KEY,
+    workspace_id         INT NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    user_id              INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role                 TEXT NOT NULL,
+    created_at           TEXT NOT NULL,
+
+    UNIQUE(workspace_id, user_id)
+);
+
+CREATE INDEX workspace_members_user_id_idx ON workspace_members (user_id);
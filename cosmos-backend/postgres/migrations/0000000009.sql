@@ -0,0 +1,2 @@
+-- The W3C traceparent of the span that created a run, so that the spans of the run join its trace.
+ALTER TABLE runs ADD COLUMN trace_parent TEXT NOT NULL DEFAULT '';
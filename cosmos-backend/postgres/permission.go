@@ -0,0 +1,194 @@
+package postgres
+
+import (
+	"context"
+	"cosmos"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+func (s *DBService) FindPermissionByID(ctx context.Context, id int) (*cosmos.Permission, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	return findPermissionByID(ctx, tx, id)
+}
+
+func (s *DBService) FindPermissions(ctx context.Context, filter cosmos.PermissionFilter) ([]*cosmos.Permission, int, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback(ctx)
+	return findPermissions(ctx, tx, filter)
+}
+
+func (s *DBService) CreatePermission(ctx context.Context, permission *cosmos.Permission) error {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := createPermission(ctx, tx, permission); err != nil {
+		return err
+	}
+
+	if err := recordAudit(ctx, tx, permission.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *DBService) DeletePermission(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := deletePermission(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if err := recordAudit(ctx, tx, id); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func findPermissionByID(ctx context.Context, tx *Tx, id int) (*cosmos.Permission, error) {
+	permissions, totalPermissions, err := findPermissions(ctx, tx, cosmos.PermissionFilter{ID: &id})
+	if err != nil {
+		return nil, err
+	} else if totalPermissions == 0 {
+		return nil, cosmos.Errorf(cosmos.ENOTFOUND, "Permission not found")
+	}
+	return permissions[0], nil
+}
+
+func findPermissions(ctx context.Context, tx *Tx, filter cosmos.PermissionFilter) ([]*cosmos.Permission, int, error) {
+	// Build the WHERE clause.
+	where, args, i := []string{"1 = 1"}, []interface{}{}, 1
+	if v := filter.ID; v != nil {
+		where, args = append(where, fmt.Sprintf("id = $%d", i)), append(args, *v)
+		i++
+	}
+	if v := filter.UserID; v != nil {
+		where, args = append(where, fmt.Sprintf("user_id = $%d", i)), append(args, *v)
+		i++
+	}
+	if v := filter.SyncID; v != nil {
+		where, args = append(where, fmt.Sprintf("sync_id = $%d", i)), append(args, *v)
+		i++
+	}
+	if v := filter.EndpointID; v != nil {
+		where, args = append(where, fmt.Sprintf("endpoint_id = $%d", i)), append(args, *v)
+		i++
+	}
+
+	// Restrict to the permissions for the syncs and endpoints in the workspace of the request.
+	if v, ok := cosmos.WorkspaceFromContext(ctx); ok {
+		where, args = append(where, fmt.Sprintf("(sync_id IN (SELECT id FROM syncs WHERE workspace_id = $%d) OR endpoint_id IN (SELECT id FROM endpoints WHERE workspace_id = $%d))", i, i)), append(args, v)
+		i++
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT
+			id,
+			user_id,
+			role,
+			sync_id,
+			endpoint_id,
+			created_at,
+			COUNT(*) OVER()
+		FROM permissions
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY id ASC
+		`+FormatLimitOffset(filter.Limit, filter.Offset),
+		args...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	// Iterate over the returned rows and deserialize into cosmos.Permission objects.
+	permissions := []*cosmos.Permission{}
+	totalPermissions := 0
+	for rows.Next() {
+		var permission cosmos.Permission
+		if err := rows.Scan(
+			&permission.ID,
+			&permission.UserID,
+			&permission.Role,
+			&permission.SyncID,
+			&permission.EndpointID,
+			(*NullTime)(&permission.CreatedAt),
+			&totalPermissions,
+		); err != nil {
+			return nil, 0, err
+		}
+		permissions = append(permissions, &permission)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return permissions, totalPermissions, nil
+}
+
+func createPermission(ctx context.Context, tx *Tx, permission *cosmos.Permission) error {
+	// Set timestamps to current time.
+	permission.CreatedAt = tx.now
+
+	// Insert permission into database.
+	err := tx.QueryRow(ctx, `
+		INSERT INTO permissions (
+			user_id,
+			role,
+			sync_id,
+			endpoint_id,
+			created_at
+		)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`,
+		permission.UserID,
+		permission.Role,
+		permission.SyncID,
+		permission.EndpointID,
+		(*NullTime)(&permission.CreatedAt),
+	).Scan(&permission.ID)
+
+	if err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
+
+func deletePermission(ctx context.Context, tx *Tx, id int) error {
+	// Verify that the permission object exists.
+	if _, err := findPermissionByID(ctx, tx, id); err != nil {
+		return err
+	}
+
+	// Remove permission from database.
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM permissions
+		WHERE id = $1
+	`,
+		id,
+	); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -127,8 +127,8 @@ func (db *DB) migrateFiles(names []string) error {
 		if err := tx.QueryRow(context.Background(), `SELECT COUNT(*) FROM migrations WHERE name = $1`, name).Scan(&n); err != nil {
 			return err
 		} else if n != 0 {
-			// Migration has already been run. Nothing more to do.
-			return nil
+			// Migration has already been run. Move on to the next one.
+			continue
 		}
 
 		// Read and execute the migration file.
@@ -264,6 +264,30 @@ func (r *RunOptions) Value() (driver.Value, error) {
 	return marshal(r)
 }
 
+// SchemaChanges represents a helper wrapper for []*cosmos.SchemaChange.
+// It automatically converts to/from string.
+type SchemaChanges []*cosmos.SchemaChange
+
+func (s *SchemaChanges) Scan(value interface{}) error {
+	return unmarshal(value, s)
+}
+
+func (s *SchemaChanges) Value() (driver.Value, error) {
+	return marshal(s)
+}
+
+// AuditChanges represents a helper wrapper for []*cosmos.AuditChange.
+// It automatically converts to/from string.
+type AuditChanges []*cosmos.AuditChange
+
+func (c *AuditChanges) Scan(value interface{}) error {
+	return unmarshal(value, c)
+}
+
+func (c *AuditChanges) Value() (driver.Value, error) {
+	return marshal(c)
+}
+
 // Map represents a helper wrapper for map[string]interface{}.
 // It automatically converts to/from string.
 type Map map[string]interface{}
@@ -291,16 +315,26 @@ func FormatLimitOffset(limit, offset int) string {
 // FormatError converts the error into proper application errors (cosmos.Error) where appropriate.
 func FormatError(err error) error {
 	errStr := err.Error()
-	if strings.Contains(errStr, `violates unique constraint "connectors_name_type_key"`) {
+	if strings.Contains(errStr, `violates unique constraint "connectors_workspace_id_name_type_key"`) {
+		return cosmos.Errorf(cosmos.ECONFLICT, "Connector already exists")
+	} else if strings.Contains(errStr, `violates unique constraint "connectors_workspace_id_docker_image_name_docker_image_tag_key"`) {
+		return cosmos.Errorf(cosmos.ECONFLICT, "Connector already exists")
+	} else if strings.Contains(errStr, `violates unique constraint "connectors_shared_name_type_idx"`) {
 		return cosmos.Errorf(cosmos.ECONFLICT, "Connector already exists")
-	} else if strings.Contains(errStr, `violates unique constraint "connectors_docker_image_name_docker_image_tag_key"`) {
+	} else if strings.Contains(errStr, `violates unique constraint "connectors_shared_docker_image_name_docker_image_tag_idx"`) {
 		return cosmos.Errorf(cosmos.ECONFLICT, "Connector already exists")
-	} else if strings.Contains(errStr, `violates unique constraint "endpoints_name_type_key"`) {
+	} else if strings.Contains(errStr, `violates unique constraint "endpoints_workspace_id_name_type_key"`) {
 		return cosmos.Errorf(cosmos.ECONFLICT, "Endpoint already exists")
-	} else if strings.Contains(errStr, `violates unique constraint "syncs_name_key"`) {
+	} else if strings.Contains(errStr, `violates unique constraint "syncs_workspace_id_name_key"`) {
 		return cosmos.Errorf(cosmos.ECONFLICT, "Sync already exists")
 	} else if strings.Contains(errStr, `violates unique constraint "runs_sync_id_execution_date_key"`) {
 		return cosmos.Errorf(cosmos.ECONFLICT, "Run already exists")
+	} else if strings.Contains(errStr, `violates unique constraint "users_username_key"`) {
+		return cosmos.Errorf(cosmos.ECONFLICT, "User already exists")
+	} else if strings.Contains(errStr, `violates unique constraint "workspaces_name_key"`) {
+		return cosmos.Errorf(cosmos.ECONFLICT, "Workspace already exists")
+	} else if strings.Contains(errStr, `on table "workspaces" violates foreign key constraint`) {
+		return cosmos.Errorf(cosmos.ECONFLICT, "Workspace is not empty")
 	}
 	return err
 }
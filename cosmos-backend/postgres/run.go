@@ -27,6 +27,16 @@ func (s *DBService) FindRuns(ctx context.Context, filter cosmos.RunFilter) ([]*c
 	return findRuns(ctx, tx, filter, true)
 }
 
+func (s *DBService) FindRunsWithoutSync(ctx context.Context, filter cosmos.RunFilter) ([]*cosmos.Run, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	runs, _, err := findRuns(ctx, tx, filter, false)
+	return runs, err
+}
+
 func (s *DBService) CreateRun(ctx context.Context, run *cosmos.Run) error {
 	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
@@ -100,6 +110,20 @@ func findRuns(ctx context.Context, tx *Tx, filter cosmos.RunFilter, wantSync boo
 		where, args = append(where, fmt.Sprintf("execution_date <= $%d", i)), append(args, v[1]+"T23:59:59Z")
 		i++
 	}
+	if v := filter.ArtifactsPurged; v != nil {
+		where, args = append(where, fmt.Sprintf("artifacts_purged = $%d", i)), append(args, *v)
+		i++
+	}
+	if v := filter.SyncIDs; v != nil {
+		where, args = append(where, fmt.Sprintf("sync_id = ANY($%d)", i)), append(args, v)
+		i++
+	}
+
+	// Restrict to the runs of the syncs in the workspace of the request.
+	if v, ok := cosmos.WorkspaceFromContext(ctx); ok {
+		where, args = append(where, fmt.Sprintf("sync_id IN (SELECT id FROM syncs WHERE workspace_id = $%d)", i)), append(args, v)
+		i++
+	}
 
 	rows, err := tx.Query(ctx, `
 		SELECT
@@ -111,6 +135,8 @@ func findRuns(ctx context.Context, tx *Tx, filter cosmos.RunFilter, wantSync boo
 			options,
 			temporal_workflow_id,
 			temporal_run_id,
+			artifacts_purged,
+			trace_parent,
 			COUNT(*) OVER()
 		FROM runs
 		WHERE `+strings.Join(where, " AND ")+`
@@ -138,6 +164,8 @@ func findRuns(ctx context.Context, tx *Tx, filter cosmos.RunFilter, wantSync boo
 			(*RunOptions)(&run.Options),
 			&run.TemporalWorkflowID,
 			&run.TemporalRunID,
+			&run.ArtifactsPurged,
+			&run.TraceParent,
 			&totalRuns,
 		); err != nil {
 			return nil, 0, err
@@ -174,9 +202,10 @@ func createRun(ctx context.Context, tx *Tx, run *cosmos.Run) error {
 			stats,
 			options,
 			temporal_workflow_id,
-			temporal_run_id
+			temporal_run_id,
+			trace_parent
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id
 	`,
 		run.SyncID,
@@ -186,6 +215,7 @@ func createRun(ctx context.Context, tx *Tx, run *cosmos.Run) error {
 		(*RunOptions)(&run.Options),
 		run.TemporalWorkflowID,
 		run.TemporalRunID,
+		run.TraceParent,
 	).Scan(&run.ID)
 
 	if err != nil {
@@ -205,9 +235,10 @@ func updateRun(ctx context.Context, tx *Tx, id int, run *cosmos.Run) error {
 			stats = $4,
 			options = $5,
 			temporal_workflow_id = $6,
-			temporal_run_id = $7
+			temporal_run_id = $7,
+			artifacts_purged = $8
 		WHERE
-			id = $8
+			id = $9
 	`,
 		run.SyncID,
 		(*NullTime)(&run.ExecutionDate),
@@ -216,6 +247,7 @@ func updateRun(ctx context.Context, tx *Tx, id int, run *cosmos.Run) error {
 		(*RunOptions)(&run.Options),
 		run.TemporalWorkflowID,
 		run.TemporalRunID,
+		run.ArtifactsPurged,
 		id,
 	); err != nil {
 		return FormatError(err)
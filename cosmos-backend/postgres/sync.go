@@ -39,6 +39,10 @@ func (s *DBService) CreateSync(ctx context.Context, sync *cosmos.Sync) error {
 		return err
 	}
 
+	if err := recordAudit(ctx, tx, sync.ID); err != nil {
+		return err
+	}
+
 	return tx.Commit(ctx)
 }
 
@@ -53,6 +57,10 @@ func (s *DBService) UpdateSync(ctx context.Context, id int, sync *cosmos.Sync) e
 		return err
 	}
 
+	if err := recordAudit(ctx, tx, id); err != nil {
+		return err
+	}
+
 	return tx.Commit(ctx)
 }
 
@@ -67,6 +75,10 @@ func (s *DBService) DeleteSync(ctx context.Context, id int) error {
 		return err
 	}
 
+	if err := recordAudit(ctx, tx, id); err != nil {
+		return err
+	}
+
 	return tx.Commit(ctx)
 }
 
@@ -91,10 +103,25 @@ func findSyncs(ctx context.Context, tx *Tx, filter cosmos.SyncFilter) ([]*cosmos
 		where, args = append(where, fmt.Sprintf("name = $%d", i)), append(args, *v)
 		i++
 	}
+	if v := filter.SourceEndpointID; v != nil {
+		where, args = append(where, fmt.Sprintf("source_endpoint_id = $%d", i)), append(args, *v)
+		i++
+	}
+	if v := filter.IDs; v != nil {
+		where, args = append(where, fmt.Sprintf("id = ANY($%d)", i)), append(args, v)
+		i++
+	}
+
+	// Restrict to the workspace of the request.
+	if v, ok := cosmos.WorkspaceFromContext(ctx); ok {
+		where, args = append(where, fmt.Sprintf("workspace_id = $%d", i)), append(args, v)
+		i++
+	}
 
 	rows, err := tx.Query(ctx, `
 		SELECT
 			id,
+			workspace_id,
 			name,
 			source_endpoint_id,
 			destination_endpoint_id,
@@ -104,6 +131,9 @@ func findSyncs(ctx context.Context, tx *Tx, filter cosmos.SyncFilter) ([]*cosmos
 			namespace_definition,
 			namespace_format,
 			stream_prefix,
+			validation_mode,
+			schema_change_policy,
+			schema_changes,
 			state,
 			config,
 			configured_catalog,
@@ -129,6 +159,7 @@ func findSyncs(ctx context.Context, tx *Tx, filter cosmos.SyncFilter) ([]*cosmos
 
 		if err := rows.Scan(
 			&sync.ID,
+			&sync.WorkspaceID,
 			&sync.Name,
 			&sync.SourceEndpointID,
 			&sync.DestinationEndpointID,
@@ -138,6 +169,9 @@ func findSyncs(ctx context.Context, tx *Tx, filter cosmos.SyncFilter) ([]*cosmos
 			&sync.NamespaceDefinition,
 			&sync.NamespaceFormat,
 			&sync.StreamPrefix,
+			&sync.ValidationMode,
+			&sync.SchemaChangePolicy,
+			(*SchemaChanges)(&sync.SchemaChanges),
 			(*Map)(&sync.State),
 			(*Form)(&sync.Config),
 			(*Message)(&sync.ConfiguredCatalog),
@@ -182,6 +216,7 @@ func createSync(ctx context.Context, tx *Tx, sync *cosmos.Sync) error {
 	// Insert sync into database.
 	err := tx.QueryRow(ctx, `
 		INSERT INTO syncs (
+			workspace_id,
 			name,
 			source_endpoint_id,
 			destination_endpoint_id,
@@ -191,15 +226,19 @@ func createSync(ctx context.Context, tx *Tx, sync *cosmos.Sync) error {
 			namespace_definition,
 			namespace_format,
 			stream_prefix,
+			validation_mode,
+			schema_change_policy,
+			schema_changes,
 			state,
 			config,
 			configured_catalog,
 			created_at,
 			updated_at
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
 		RETURNING id
 	`,
+		sync.WorkspaceID,
 		sync.Name,
 		sync.SourceEndpointID,
 		sync.DestinationEndpointID,
@@ -209,6 +248,9 @@ func createSync(ctx context.Context, tx *Tx, sync *cosmos.Sync) error {
 		sync.NamespaceDefinition,
 		sync.NamespaceFormat,
 		sync.StreamPrefix,
+		sync.ValidationMode,
+		sync.SchemaChangePolicy,
+		(*SchemaChanges)(&sync.SchemaChanges),
 		(*Map)(&sync.State),
 		(*Form)(&sync.Config),
 		(*Message)(&sync.ConfiguredCatalog),
@@ -239,12 +281,15 @@ func updateSync(ctx context.Context, tx *Tx, id int, sync *cosmos.Sync) error {
 			namespace_definition = $7,
 			namespace_format = $8,
 			stream_prefix = $9,
-			state = $10,
-			config = $11,
-			configured_catalog = $12,
-			updated_at = $13
+			validation_mode = $10,
+			schema_change_policy = $11,
+			schema_changes = $12,
+			state = $13,
+			config = $14,
+			configured_catalog = $15,
+			updated_at = $16
 		WHERE
-			id = $14
+			id = $17
 	`,
 		sync.Name,
 		sync.SourceEndpointID,
@@ -255,6 +300,9 @@ func updateSync(ctx context.Context, tx *Tx, id int, sync *cosmos.Sync) error {
 		sync.NamespaceDefinition,
 		sync.NamespaceFormat,
 		sync.StreamPrefix,
+		sync.ValidationMode,
+		sync.SchemaChangePolicy,
+		(*SchemaChanges)(&sync.SchemaChanges),
 		(*Map)(&sync.State),
 		(*Form)(&sync.Config),
 		(*Message)(&sync.ConfiguredCatalog),
@@ -0,0 +1,239 @@
+package postgres
+
+import (
+	"context"
+	"cosmos"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+func (s *DBService) FindTokenByID(ctx context.Context, id int) (*cosmos.Token, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	return findTokenByID(ctx, tx, id)
+}
+
+func (s *DBService) FindTokens(ctx context.Context, filter cosmos.TokenFilter) ([]*cosmos.Token, int, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback(ctx)
+	return findTokens(ctx, tx, filter)
+}
+
+func (s *DBService) CreateToken(ctx context.Context, token *cosmos.Token) error {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := createToken(ctx, tx, token); err != nil {
+		return err
+	}
+
+	if err := recordAudit(ctx, tx, token.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *DBService) TouchToken(ctx context.Context, id int, lastUsedAt time.Time) error {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE tokens
+		SET last_used_at = $1
+		WHERE id = $2
+	`,
+		(*NullTime)(&lastUsedAt),
+		id,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *DBService) DeleteToken(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := deleteToken(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if err := recordAudit(ctx, tx, id); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *DBService) DeleteExpiredTokens(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Timestamps are stored in RFC 3339 format in UTC. So, they can be compared as strings.
+	now := tx.now
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM tokens
+		WHERE expires_at IS NOT NULL AND expires_at < $1
+	`,
+		(*NullTime)(&now),
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func findTokenByID(ctx context.Context, tx *Tx, id int) (*cosmos.Token, error) {
+	tokens, totalTokens, err := findTokens(ctx, tx, cosmos.TokenFilter{ID: &id})
+	if err != nil {
+		return nil, err
+	} else if totalTokens == 0 {
+		return nil, cosmos.Errorf(cosmos.ENOTFOUND, "Token not found")
+	}
+	return tokens[0], nil
+}
+
+func findTokens(ctx context.Context, tx *Tx, filter cosmos.TokenFilter) ([]*cosmos.Token, int, error) {
+	// Build the WHERE clause.
+	where, args, i := []string{"1 = 1"}, []interface{}{}, 1
+	if v := filter.ID; v != nil {
+		where, args = append(where, fmt.Sprintf("id = $%d", i)), append(args, *v)
+		i++
+	}
+	if v := filter.UserID; v != nil {
+		where, args = append(where, fmt.Sprintf("user_id = $%d", i)), append(args, *v)
+		i++
+	}
+	if v := filter.Hash; v != nil {
+		where, args = append(where, fmt.Sprintf("hash = $%d", i)), append(args, *v)
+		i++
+	}
+	if v := filter.Session; v != nil {
+		where, args = append(where, fmt.Sprintf("session = $%d", i)), append(args, *v)
+		i++
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT
+			id,
+			name,
+			user_id,
+			hash,
+			session,
+			expires_at,
+			last_used_at,
+			created_at,
+			COUNT(*) OVER()
+		FROM tokens
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY created_at DESC, id DESC
+		`+FormatLimitOffset(filter.Limit, filter.Offset),
+		args...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	// Iterate over the returned rows and deserialize into cosmos.Token objects.
+	tokens := []*cosmos.Token{}
+	totalTokens := 0
+	for rows.Next() {
+		var token cosmos.Token
+		if err := rows.Scan(
+			&token.ID,
+			&token.Name,
+			&token.UserID,
+			&token.Hash,
+			&token.Session,
+			(*NullTime)(&token.ExpiresAt),
+			(*NullTime)(&token.LastUsedAt),
+			(*NullTime)(&token.CreatedAt),
+			&totalTokens,
+		); err != nil {
+			return nil, 0, err
+		}
+		tokens = append(tokens, &token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return tokens, totalTokens, nil
+}
+
+func createToken(ctx context.Context, tx *Tx, token *cosmos.Token) error {
+	// Set timestamps to current time.
+	token.CreatedAt = tx.now
+
+	// Insert token into database.
+	err := tx.QueryRow(ctx, `
+		INSERT INTO tokens (
+			name,
+			user_id,
+			hash,
+			session,
+			expires_at,
+			last_used_at,
+			created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`,
+		token.Name,
+		token.UserID,
+		token.Hash,
+		token.Session,
+		(*NullTime)(&token.ExpiresAt),
+		(*NullTime)(&token.LastUsedAt),
+		(*NullTime)(&token.CreatedAt),
+	).Scan(&token.ID)
+
+	if err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
+
+func deleteToken(ctx context.Context, tx *Tx, id int) error {
+	// Verify that the token object exists.
+	if _, err := findTokenByID(ctx, tx, id); err != nil {
+		return err
+	}
+
+	// Remove token from database.
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM tokens
+		WHERE id = $1
+	`,
+		id,
+	); err != nil {
+		return err
+	}
+
+	return nil
+}
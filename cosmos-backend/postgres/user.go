@@ -0,0 +1,223 @@
+package postgres
+
+import (
+	"context"
+	"cosmos"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+func (s *DBService) FindUserByID(ctx context.Context, id int) (*cosmos.User, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	return findUserByID(ctx, tx, id)
+}
+
+func (s *DBService) FindUsers(ctx context.Context, filter cosmos.UserFilter) ([]*cosmos.User, int, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback(ctx)
+	return findUsers(ctx, tx, filter)
+}
+
+func (s *DBService) CreateUser(ctx context.Context, user *cosmos.User) error {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := createUser(ctx, tx, user); err != nil {
+		return err
+	}
+
+	if err := recordAudit(ctx, tx, user.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *DBService) UpdateUser(ctx context.Context, id int, user *cosmos.User) error {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := updateUser(ctx, tx, id, user); err != nil {
+		return err
+	}
+
+	if err := recordAudit(ctx, tx, id); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *DBService) DeleteUser(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := deleteUser(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if err := recordAudit(ctx, tx, id); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func findUserByID(ctx context.Context, tx *Tx, id int) (*cosmos.User, error) {
+	users, totalUsers, err := findUsers(ctx, tx, cosmos.UserFilter{ID: &id})
+	if err != nil {
+		return nil, err
+	} else if totalUsers == 0 {
+		return nil, cosmos.Errorf(cosmos.ENOTFOUND, "User not found")
+	}
+	return users[0], nil
+}
+
+func findUsers(ctx context.Context, tx *Tx, filter cosmos.UserFilter) ([]*cosmos.User, int, error) {
+	// Build the WHERE clause.
+	where, args, i := []string{"1 = 1"}, []interface{}{}, 1
+	if v := filter.ID; v != nil {
+		where, args = append(where, fmt.Sprintf("id = $%d", i)), append(args, *v)
+		i++
+	}
+	if v := filter.Username; v != nil {
+		where, args = append(where, fmt.Sprintf("username = $%d", i)), append(args, *v)
+		i++
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT
+			id,
+			username,
+			role,
+			password_hash,
+			created_at,
+			updated_at,
+			COUNT(*) OVER()
+		FROM users
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY username ASC
+		`+FormatLimitOffset(filter.Limit, filter.Offset),
+		args...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	// Iterate over the returned rows and deserialize into cosmos.User objects.
+	users := []*cosmos.User{}
+	totalUsers := 0
+	for rows.Next() {
+		var user cosmos.User
+		if err := rows.Scan(
+			&user.ID,
+			&user.Username,
+			&user.Role,
+			&user.PasswordHash,
+			(*NullTime)(&user.CreatedAt),
+			(*NullTime)(&user.UpdatedAt),
+			&totalUsers,
+		); err != nil {
+			return nil, 0, err
+		}
+		users = append(users, &user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return users, totalUsers, nil
+}
+
+func createUser(ctx context.Context, tx *Tx, user *cosmos.User) error {
+	// Set timestamps to current time.
+	user.CreatedAt = tx.now
+	user.UpdatedAt = user.CreatedAt
+
+	// Insert user into database.
+	err := tx.QueryRow(ctx, `
+		INSERT INTO users (
+			username,
+			role,
+			password_hash,
+			created_at,
+			updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`,
+		user.Username,
+		user.Role,
+		user.PasswordHash,
+		(*NullTime)(&user.CreatedAt),
+		(*NullTime)(&user.UpdatedAt),
+	).Scan(&user.ID)
+
+	if err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
+
+func updateUser(ctx context.Context, tx *Tx, id int, user *cosmos.User) error {
+	user.UpdatedAt = tx.now
+
+	// Execute update query.
+	if _, err := tx.Exec(ctx, `
+		UPDATE users
+		SET
+			role = $1,
+			password_hash = $2,
+			updated_at = $3
+		WHERE
+			id = $4
+	`,
+		user.Role,
+		user.PasswordHash,
+		(*NullTime)(&user.UpdatedAt),
+		id,
+	); err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
+
+func deleteUser(ctx context.Context, tx *Tx, id int) error {
+	// Verify that the user object exists.
+	if _, err := findUserByID(ctx, tx, id); err != nil {
+		return err
+	}
+
+	// Remove user from database. The tokens and permissions of the user are removed along with it.
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM users
+		WHERE id = $1
+	`,
+		id,
+	); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,387 @@
+package postgres
+
+import (
+	"context"
+	"cosmos"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+func (s *DBService) FindWorkspaceByID(ctx context.Context, id int) (*cosmos.Workspace, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	return findWorkspaceByID(ctx, tx, id)
+}
+
+func (s *DBService) FindWorkspaces(ctx context.Context, filter cosmos.WorkspaceFilter) ([]*cosmos.Workspace, int, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback(ctx)
+	return findWorkspaces(ctx, tx, filter)
+}
+
+func (s *DBService) CreateWorkspace(ctx context.Context, workspace *cosmos.Workspace) error {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := createWorkspace(ctx, tx, workspace); err != nil {
+		return err
+	}
+
+	if err := recordAudit(ctx, tx, workspace.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *DBService) UpdateWorkspace(ctx context.Context, id int, workspace *cosmos.Workspace) error {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := updateWorkspace(ctx, tx, id, workspace); err != nil {
+		return err
+	}
+
+	if err := recordAudit(ctx, tx, id); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *DBService) DeleteWorkspace(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := deleteWorkspace(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if err := recordAudit(ctx, tx, id); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *DBService) FindWorkspaceMemberByID(ctx context.Context, id int) (*cosmos.WorkspaceMember, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	return findWorkspaceMemberByID(ctx, tx, id)
+}
+
+func (s *DBService) FindWorkspaceMembers(ctx context.Context, filter cosmos.WorkspaceMemberFilter) ([]*cosmos.WorkspaceMember, int, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback(ctx)
+	return findWorkspaceMembers(ctx, tx, filter)
+}
+
+func (s *DBService) CreateWorkspaceMember(ctx context.Context, member *cosmos.WorkspaceMember) error {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := createWorkspaceMember(ctx, tx, member); err != nil {
+		return err
+	}
+
+	if err := recordAudit(ctx, tx, member.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *DBService) DeleteWorkspaceMember(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := deleteWorkspaceMember(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if err := recordAudit(ctx, tx, id); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func findWorkspaceByID(ctx context.Context, tx *Tx, id int) (*cosmos.Workspace, error) {
+	workspaces, totalWorkspaces, err := findWorkspaces(ctx, tx, cosmos.WorkspaceFilter{ID: &id})
+	if err != nil {
+		return nil, err
+	} else if totalWorkspaces == 0 {
+		return nil, cosmos.Errorf(cosmos.ENOTFOUND, "Workspace not found")
+	}
+	return workspaces[0], nil
+}
+
+func findWorkspaces(ctx context.Context, tx *Tx, filter cosmos.WorkspaceFilter) ([]*cosmos.Workspace, int, error) {
+	// Build the WHERE clause.
+	where, args, i := []string{"1 = 1"}, []interface{}{}, 1
+	if v := filter.ID; v != nil {
+		where, args = append(where, fmt.Sprintf("id = $%d", i)), append(args, *v)
+		i++
+	}
+	if v := filter.Name; v != nil {
+		where, args = append(where, fmt.Sprintf("name = $%d", i)), append(args, *v)
+		i++
+	}
+	if v := filter.UserID; v != nil {
+		where, args = append(where, fmt.Sprintf("(id = %d OR id IN (SELECT workspace_id FROM workspace_members WHERE user_id = $%d))", cosmos.DefaultWorkspaceID, i)), append(args, *v)
+		i++
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT
+			id,
+			name,
+			created_at,
+			updated_at,
+			COUNT(*) OVER()
+		FROM workspaces
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY name ASC
+		`+FormatLimitOffset(filter.Limit, filter.Offset),
+		args...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	// Iterate over the returned rows and deserialize into cosmos.Workspace objects.
+	workspaces := []*cosmos.Workspace{}
+	totalWorkspaces := 0
+	for rows.Next() {
+		var workspace cosmos.Workspace
+		if err := rows.Scan(
+			&workspace.ID,
+			&workspace.Name,
+			(*NullTime)(&workspace.CreatedAt),
+			(*NullTime)(&workspace.UpdatedAt),
+			&totalWorkspaces,
+		); err != nil {
+			return nil, 0, err
+		}
+		workspaces = append(workspaces, &workspace)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return workspaces, totalWorkspaces, nil
+}
+
+func createWorkspace(ctx context.Context, tx *Tx, workspace *cosmos.Workspace) error {
+	// Set timestamps to current time.
+	workspace.CreatedAt = tx.now
+	workspace.UpdatedAt = workspace.CreatedAt
+
+	// Insert workspace into database.
+	err := tx.QueryRow(ctx, `
+		INSERT INTO workspaces (
+			name,
+			created_at,
+			updated_at
+		)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`,
+		workspace.Name,
+		(*NullTime)(&workspace.CreatedAt),
+		(*NullTime)(&workspace.UpdatedAt),
+	).Scan(&workspace.ID)
+
+	if err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
+
+func updateWorkspace(ctx context.Context, tx *Tx, id int, workspace *cosmos.Workspace) error {
+	workspace.UpdatedAt = tx.now
+
+	// Execute update query.
+	if _, err := tx.Exec(ctx, `
+		UPDATE workspaces
+		SET
+			name = $1,
+			updated_at = $2
+		WHERE
+			id = $3
+	`,
+		workspace.Name,
+		(*NullTime)(&workspace.UpdatedAt),
+		id,
+	); err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
+
+func deleteWorkspace(ctx context.Context, tx *Tx, id int) error {
+	// Verify that the workspace object exists.
+	if _, err := findWorkspaceByID(ctx, tx, id); err != nil {
+		return err
+	}
+
+	// Remove workspace from database. This fails if the workspace still has endpoints, syncs or private connectors.
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM workspaces
+		WHERE id = $1
+	`,
+		id,
+	); err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
+
+func findWorkspaceMemberByID(ctx context.Context, tx *Tx, id int) (*cosmos.WorkspaceMember, error) {
+	members, totalMembers, err := findWorkspaceMembers(ctx, tx, cosmos.WorkspaceMemberFilter{ID: &id})
+	if err != nil {
+		return nil, err
+	} else if totalMembers == 0 {
+		return nil, cosmos.Errorf(cosmos.ENOTFOUND, "Workspace member not found")
+	}
+	return members[0], nil
+}
+
+func findWorkspaceMembers(ctx context.Context, tx *Tx, filter cosmos.WorkspaceMemberFilter) ([]*cosmos.WorkspaceMember, int, error) {
+	// Build the WHERE clause.
+	where, args, i := []string{"1 = 1"}, []interface{}{}, 1
+	if v := filter.ID; v != nil {
+		where, args = append(where, fmt.Sprintf("id = $%d", i)), append(args, *v)
+		i++
+	}
+	if v := filter.WorkspaceID; v != nil {
+		where, args = append(where, fmt.Sprintf("workspace_id = $%d", i)), append(args, *v)
+		i++
+	}
+	if v := filter.UserID; v != nil {
+		where, args = append(where, fmt.Sprintf("user_id = $%d", i)), append(args, *v)
+		i++
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT
+			id,
+			workspace_id,
+			user_id,
+			role,
+			created_at,
+			COUNT(*) OVER()
+		FROM workspace_members
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY id ASC
+		`+FormatLimitOffset(filter.Limit, filter.Offset),
+		args...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	// Iterate over the returned rows and deserialize into cosmos.WorkspaceMember objects.
+	members := []*cosmos.WorkspaceMember{}
+	totalMembers := 0
+	for rows.Next() {
+		var member cosmos.WorkspaceMember
+		if err := rows.Scan(
+			&member.ID,
+			&member.WorkspaceID,
+			&member.UserID,
+			&member.Role,
+			(*NullTime)(&member.CreatedAt),
+			&totalMembers,
+		); err != nil {
+			return nil, 0, err
+		}
+		members = append(members, &member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return members, totalMembers, nil
+}
+
+func createWorkspaceMember(ctx context.Context, tx *Tx, member *cosmos.WorkspaceMember) error {
+	// Set timestamps to current time.
+	member.CreatedAt = tx.now
+
+	// Insert workspace member into database.
+	err := tx.QueryRow(ctx, `
+		INSERT INTO workspace_members (
+			workspace_id,
+			user_id,
+			role,
+			created_at
+		)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`,
+		member.WorkspaceID,
+		member.UserID,
+		member.Role,
+		(*NullTime)(&member.CreatedAt),
+	).Scan(&member.ID)
+
+	if err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
+
+func deleteWorkspaceMember(ctx context.Context, tx *Tx, id int) error {
+	// Verify that the workspace member object exists.
+	if _, err := findWorkspaceMemberByID(ctx, tx, id); err != nil {
+		return err
+	}
+
+	// Remove workspace member from database.
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM workspace_members
+		WHERE id = $1
+	`,
+		id,
+	); err != nil {
+		return err
+	}
+
+	return nil
+}
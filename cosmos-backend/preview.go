@@ -0,0 +1,208 @@
+package cosmos
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// SampleRecordsPerStream is the number of records of each stream that are kept in the sample artifact of a run.
+	SampleRecordsPerStream = 10
+
+	// DefaultPreviewLimit and MaxPreviewLimit bound the number of records returned in a preview.
+	DefaultPreviewLimit = 20
+	MaxPreviewLimit     = 1000
+
+	// previewTimeout is the maximum time for which the source connector is run in a preview.
+	previewTimeout = 2 * time.Minute
+)
+
+// Preview contains the first few records of a stream read from a source endpoint.
+type Preview struct {
+	Stream  string                   `json:"stream"`
+	Fields  []*FieldPreview          `json:"fields"`
+	Records []map[string]interface{} `json:"records"`
+}
+
+// FieldPreview contains the JSON types inferred for a top-level field of the previewed records
+// along with the type declared in the JSON schema of the stream.
+type FieldPreview struct {
+	Name         string   `json:"name"`
+	Types        []string `json:"types"`
+	DeclaredType string   `json:"declaredType,omitempty"`
+}
+
+// PreviewStream reads the first limit records of a stream of a source endpoint.
+// The source connector is stopped as soon as enough records have been read.
+func (a *App) PreviewStream(ctx context.Context, endpointID int, streamKey string, limit int) (*Preview, error) {
+	if limit <= 0 {
+		limit = DefaultPreviewLimit
+	}
+	if limit > MaxPreviewLimit {
+		return nil, Errorf(EINVALID, "A preview can contain at most %d records", MaxPreviewLimit)
+	}
+
+	endpoint, err := a.FindEndpointByID(ctx, endpointID)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.Type != ConnectorTypeSource {
+		return nil, Errorf(EINVALID, "Only source endpoints can be previewed")
+	}
+
+	var stream *Stream
+	if endpoint.Catalog.Catalog != nil {
+		for i := range endpoint.Catalog.Catalog.Streams {
+			if endpoint.Catalog.Catalog.Streams[i].Key() == streamKey {
+				stream = &endpoint.Catalog.Catalog.Streams[i]
+			}
+		}
+	}
+	if stream == nil {
+		return nil, Errorf(ENOTFOUND, "Stream %s does not exist in the catalog of endpoint %s", streamKey, endpoint.Name)
+	}
+
+	// The fields which are masked by the syncs of the stream are masked in the preview as well,
+	// so that the preview doesn't reveal more of the records than the syncs do.
+	policies, err := a.streamFieldPolicies(ctx, endpoint.ID, streamKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the stream from the beginning. Without state, incremental streams are read in full as well.
+	configuredStream := map[string]interface{}{
+		"stream":                stream,
+		"sync_mode":             SyncModeFullRefresh,
+		"destination_sync_mode": DestinationSyncModeAppend,
+	}
+	if !contains(stream.SupportedSyncModes, SyncModeFullRefresh) {
+		configuredStream["sync_mode"] = SyncModeIncremental
+		configuredStream["cursor_field"] = stream.DefaultCursorField
+	}
+	configuredCatalog := map[string]interface{}{
+		"streams": []interface{}{configuredStream},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, previewTimeout)
+	defer cancel()
+
+	config, err := a.ConnectorConfig(ctx, &endpoint.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	out, errc := a.ReadStream(ctx, endpoint.Connector, config, configuredCatalog)
+
+	preview := &Preview{Stream: streamKey, Records: []map[string]interface{}{}}
+	var lastError string
+
+	for line := range out {
+		msg, ok := line.(*Message)
+		if !ok {
+			continue
+		}
+		if msg.Type == MessageTypeLog && (msg.Log.Level == LogLevelError || msg.Log.Level == LogLevelFatal) {
+			lastError = msg.Log.Message
+		}
+		if msg.Type != MessageTypeRecord || msg.Record.StreamKey() != streamKey || len(preview.Records) == limit {
+			continue
+		}
+		preview.Records = append(preview.Records, msg.Record.Data)
+		if len(preview.Records) == limit {
+			// Stop the source connector. The remaining output is drained so that it can exit.
+			cancel()
+		}
+	}
+
+	if err := <-errc; err != nil && len(preview.Records) < limit {
+		if lastError == "" {
+			lastError = err.Error()
+		}
+		return nil, Errorf(EINVALID, "Unable to read stream %s: %s", streamKey, lastError)
+	}
+
+	// Mask the fields according to the field policies.
+	for _, record := range preview.Records {
+		for _, policy := range policies {
+			policy.Apply(record)
+		}
+	}
+
+	preview.Fields = inferFieldTypes(stream, preview.Records)
+
+	return preview, nil
+}
+
+// streamFieldPolicies returns the field policies of all the syncs which read a stream from a source endpoint.
+func (a *App) streamFieldPolicies(ctx context.Context, endpointID int, streamKey string) ([]*FieldPolicy, error) {
+	syncs, _, err := a.FindSyncs(ctx, SyncFilter{SourceEndpointID: &endpointID})
+	if err != nil {
+		return nil, err
+	}
+
+	policies := []*FieldPolicy{}
+	for _, sync := range syncs {
+		policies = append(policies, sync.Config.FieldPolicies()[streamKey]...)
+	}
+
+	return policies, nil
+}
+
+// inferFieldTypes returns the JSON types of the top-level fields across all the records.
+func inferFieldTypes(stream *Stream, records []map[string]interface{}) []*FieldPreview {
+	declared := fieldTypes(stream)
+	types := map[string]map[string]bool{}
+
+	for _, record := range records {
+		for name, value := range record {
+			if types[name] == nil {
+				types[name] = map[string]bool{}
+			}
+			types[name][InferType(value)] = true
+		}
+	}
+
+	fields := []*FieldPreview{}
+	for name, set := range types {
+		field := &FieldPreview{Name: name, Types: []string{}, DeclaredType: declared[name]}
+		for t := range set {
+			field.Types = append(field.Types, t)
+		}
+		sort.Strings(field.Types)
+		fields = append(fields, field)
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Name < fields[j].Name
+	})
+
+	return fields
+}
+
+// InferType returns the JSON schema type of a value decoded from JSON.
+func InferType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		if _, err := time.Parse(time.RFC3339, v); err == nil {
+			return "string(date-time)"
+		}
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
@@ -0,0 +1,134 @@
+package prometheus
+
+import (
+	"cosmos"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var _ cosmos.MetricsService = (*MetricsService)(nil)
+
+// Runs take minutes to hours. Everything else takes milliseconds to minutes.
+var (
+	runBuckets     = []float64{10, 30, 60, 300, 600, 1800, 3600, 3 * 3600, 6 * 3600, 24 * 3600}
+	commandBuckets = []float64{.1, .5, 1, 5, 10, 30, 60, 300, 1800, 3600}
+)
+
+// MetricsService records metrics in a prometheus registry of its own
+// so that each daemon only exposes the metrics that it records.
+type MetricsService struct {
+	registry *prometheus.Registry
+
+	runDuration      *prometheus.HistogramVec
+	records          *prometheus.CounterVec
+	queueDepth       prometheus.Gauge
+	commandDuration  *prometheus.HistogramVec
+	commandFailures  *prometheus.CounterVec
+	activityDuration *prometheus.HistogramVec
+	activityFailures *prometheus.CounterVec
+	httpDuration     *prometheus.HistogramVec
+}
+
+func NewMetricsService() *MetricsService {
+	s := &MetricsService{
+		registry: prometheus.NewRegistry(),
+
+		runDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "cosmos_run_duration_seconds",
+			Help:    "Duration of the runs that finished, by sync and status.",
+			Buckets: runBuckets,
+		}, []string{"sync_id", "status"}),
+
+		records: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cosmos_records_total",
+			Help: "Records sent to the destination, by sync and stream.",
+		}, []string{"sync_id", "stream"}),
+
+		queueDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "cosmos_scheduler_queue_depth",
+			Help: "Runs that are queued and waiting to be started.",
+		}),
+
+		commandDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "cosmos_docker_command_duration_seconds",
+			Help:    "Duration of the docker commands of connectors, by connector image and command.",
+			Buckets: commandBuckets,
+		}, []string{"connector", "command"}),
+
+		commandFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cosmos_docker_command_failures_total",
+			Help: "Docker commands of connectors that failed, by connector image and command.",
+		}, []string{"connector", "command"}),
+
+		activityDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "cosmos_activity_duration_seconds",
+			Help:    "Duration of the temporal activities of the ingestion workflow, by activity.",
+			Buckets: commandBuckets,
+		}, []string{"activity"}),
+
+		activityFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cosmos_activity_failures_total",
+			Help: "Temporal activities of the ingestion workflow that failed, by activity.",
+		}, []string{"activity"}),
+
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "cosmos_http_request_duration_seconds",
+			Help:    "Duration of API requests, by route, method and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method", "code"}),
+	}
+
+	s.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		s.runDuration,
+		s.records,
+		s.queueDepth,
+		s.commandDuration,
+		s.commandFailures,
+		s.activityDuration,
+		s.activityFailures,
+		s.httpDuration,
+	)
+
+	return s
+}
+
+func (s *MetricsService) ObserveRun(syncID int, status string, duration time.Duration) {
+	s.runDuration.WithLabelValues(strconv.Itoa(syncID), status).Observe(duration.Seconds())
+}
+
+func (s *MetricsService) ObserveRecords(syncID int, stream string, n int) {
+	s.records.WithLabelValues(strconv.Itoa(syncID), stream).Add(float64(n))
+}
+
+func (s *MetricsService) ObserveQueueDepth(n int) {
+	s.queueDepth.Set(float64(n))
+}
+
+func (s *MetricsService) ObserveCommand(connector, command string, duration time.Duration, err error) {
+	s.commandDuration.WithLabelValues(connector, command).Observe(duration.Seconds())
+	if err != nil {
+		s.commandFailures.WithLabelValues(connector, command).Inc()
+	}
+}
+
+func (s *MetricsService) ObserveActivity(activity string, duration time.Duration, err error) {
+	s.activityDuration.WithLabelValues(activity).Observe(duration.Seconds())
+	if err != nil {
+		s.activityFailures.WithLabelValues(activity).Inc()
+	}
+}
+
+func (s *MetricsService) ObserveHTTPRequest(route, method string, code int, duration time.Duration) {
+	s.httpDuration.WithLabelValues(route, method, strconv.Itoa(code)).Observe(duration.Seconds())
+}
+
+func (s *MetricsService) MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(s.registry, promhttp.HandlerOpts{})
+}
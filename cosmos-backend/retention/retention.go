@@ -0,0 +1,196 @@
+package retention
+
+import (
+	"context"
+	"cosmos"
+	"log"
+	"runtime/debug"
+	"sort"
+	"time"
+)
+
+// Policy limits the artifacts that are kept around. A zero value for any of the
+// limits means that the limit is not enforced.
+type Policy struct {
+	// MaxAge is the maximum age of a run (by execution date) whose artifacts are kept.
+	MaxAge time.Duration
+
+	// MaxRunsPerSync is the maximum number of runs of each sync whose artifacts are kept.
+	MaxRunsPerSync int
+
+	// MaxTotalBytes is the disk budget for the artifacts of all runs.
+	// The oldest artifacts are purged first when the budget is exceeded.
+	MaxTotalBytes int64
+}
+
+// DefaultPolicy is the retention policy used when none is configured.
+var DefaultPolicy = Policy{
+	MaxAge:         30 * 24 * time.Hour,
+	MaxRunsPerSync: 100,
+	MaxTotalBytes:  10 << 30,
+}
+
+// Retainer periodically purges the artifacts of finished runs according to the retention policy.
+//
+// The artifacts of the latest successful and the latest failed run of every sync
+// are always kept, irrespective of the policy.
+type Retainer struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	policy   Policy
+	interval time.Duration
+
+	*cosmos.App
+}
+
+func NewRetainer(policy Policy, interval time.Duration) *Retainer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Retainer{
+		ctx:      ctx,
+		cancel:   cancel,
+		policy:   policy,
+		interval: interval,
+	}
+}
+
+func (r *Retainer) Open() error {
+	go r.RetentionLoop(r.ctx)
+	return nil
+}
+
+func (r *Retainer) Close() error {
+	r.cancel()
+	return nil
+}
+
+func recoverFromPanic() {
+	if err := recover(); err != nil {
+		log.Printf("retention panic: %s", err)
+		debug.PrintStack()
+	}
+}
+
+func (r *Retainer) RetentionLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.interval):
+			if err := r.Purge(ctx); err != nil {
+				log.Printf("retention err: %s", err)
+			}
+		}
+	}
+}
+
+// Purge removes the artifacts of all the runs that fall outside the retention policy.
+// Runs whose artifacts cannot be measured or purged are logged and skipped until the next purge.
+func (r *Retainer) Purge(ctx context.Context) error {
+	defer recoverFromPanic()
+
+	// The syncs of the runs aren't needed, only their IDs.
+	purged := false
+	runs, err := r.App.FindRunsWithoutSync(ctx, cosmos.RunFilter{ArtifactsPurged: &purged})
+	if err != nil {
+		return err
+	}
+
+	// Runs are ordered by execution date, newest first.
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].ExecutionDate.After(runs[j].ExecutionDate)
+	})
+
+	protected := protectedRuns(runs)
+	expired := map[int]bool{}
+	perSync := map[int]int{}
+
+	for _, run := range runs {
+		perSync[run.SyncID]++
+		if !run.IsTerminalState() || protected[run.ID] {
+			continue
+		}
+		if r.policy.MaxAge > 0 && time.Since(run.ExecutionDate) > r.policy.MaxAge {
+			expired[run.ID] = true
+		}
+		if r.policy.MaxRunsPerSync > 0 && perSync[run.SyncID] > r.policy.MaxRunsPerSync {
+			expired[run.ID] = true
+		}
+	}
+
+	// Purge the oldest runs until the remaining artifacts fit within the disk budget.
+	// The artifacts are only measured if there is a budget since it takes a stat of every artifactory.
+	if r.policy.MaxTotalBytes > 0 {
+		sizes := map[int]int64{}
+		total := int64(0)
+		for _, run := range runs {
+			if expired[run.ID] {
+				continue
+			}
+			// The artifactory is looked up rather than created so that purging doesn't leave empty directories behind.
+			artifactory := r.App.LookupArtifactory(run.SyncID, run.ExecutionDate)
+			size, err := r.App.GetArtifactorySize(artifactory)
+			if err != nil {
+				log.Printf("retention: cannot get size of artifacts of run %d: %s", run.ID, err)
+				continue
+			}
+			sizes[run.ID] = size
+			total += size
+		}
+
+		for i := len(runs) - 1; i >= 0 && total > r.policy.MaxTotalBytes; i-- {
+			run := runs[i]
+			if !run.IsTerminalState() || protected[run.ID] || expired[run.ID] {
+				continue
+			}
+			expired[run.ID] = true
+			total -= sizes[run.ID]
+		}
+	}
+
+	for _, run := range runs {
+		if !expired[run.ID] {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := r.purgeRun(ctx, run); err != nil {
+			log.Printf("retention: cannot purge artifacts of run %d: %s", run.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *Retainer) purgeRun(ctx context.Context, run *cosmos.Run) error {
+	artifactory := r.App.LookupArtifactory(run.SyncID, run.ExecutionDate)
+	if err := r.App.DeleteArtifactory(artifactory); err != nil {
+		return err
+	}
+
+	purged := true
+	_, err := r.App.UpdateRun(ctx, run.ID, &cosmos.RunUpdate{ArtifactsPurged: &purged})
+	return err
+}
+
+// protectedRuns returns the IDs of the latest successful and the latest failed run
+// of every sync. The runs must be ordered by execution date, newest first.
+func protectedRuns(runs []*cosmos.Run) map[int]bool {
+	protected := map[int]bool{}
+	seen := map[int]map[string]bool{}
+
+	for _, run := range runs {
+		if run.Status != cosmos.RunStatusSuccess && run.Status != cosmos.RunStatusFailed {
+			continue
+		}
+		if seen[run.SyncID] == nil {
+			seen[run.SyncID] = map[string]bool{}
+		}
+		if !seen[run.SyncID][run.Status] {
+			seen[run.SyncID][run.Status] = true
+			protected[run.ID] = true
+		}
+	}
+
+	return protected
+}
@@ -28,7 +28,12 @@ type Run struct {
 	Options            RunOptions `json:"options"`
 	TemporalWorkflowID string     `json:"temporalWorkflowID"`
 	TemporalRunID      string     `json:"temporalRunID"`
+	ArtifactsPurged    bool       `json:"artifactsPurged"`
 	Sync               *Sync      `json:"sync"`
+
+	// TraceParent is the W3C traceparent of the span in which the run was created, e.g, the span of the
+	// request to sync now. The worker continues the trace when it starts the run. It is empty if there was no span.
+	TraceParent string `json:"-"`
 }
 
 func (r *Run) IsTerminalState() bool {
@@ -41,9 +46,12 @@ func (r *Run) IsTerminalState() bool {
 }
 
 type RunStats struct {
-	NumRecords     uint64    `json:"numRecords"`
-	ExecutionStart time.Time `json:"executionStart"`
-	ExecutionEnd   time.Time `json:"executionEnd"`
+	NumRecords            uint64    `json:"numRecords"`
+	NumInvalidRecords     uint64    `json:"numInvalidRecords"`
+	NumQuarantinedRecords uint64    `json:"numQuarantinedRecords"`
+	NumFilteredRecords    uint64    `json:"numFilteredRecords"`
+	ExecutionStart        time.Time `json:"executionStart"`
+	ExecutionEnd          time.Time `json:"executionEnd"`
 }
 
 type RunOptions struct {
@@ -51,21 +59,29 @@ type RunOptions struct {
 }
 
 type RunUpdate struct {
-	Status             *string     `json:"status"`
-	Retries            *int        `json:"retries"`
-	NumRecords         *uint64     `json:"numRecords"`
-	ExecutionStart     *time.Time  `json:"executionStart"`
-	ExecutionEnd       *time.Time  `json:"executionEnd"`
-	Options            *RunOptions `json:"options"`
-	TemporalWorkflowID *string     `json:"temporalWorkflowID"`
-	TemporalRunID      *string     `json:"temporalRunID"`
+	Status                *string     `json:"status"`
+	Retries               *int        `json:"retries"`
+	NumRecords            *uint64     `json:"numRecords"`
+	NumInvalidRecords     *uint64     `json:"numInvalidRecords"`
+	NumQuarantinedRecords *uint64     `json:"numQuarantinedRecords"`
+	NumFilteredRecords    *uint64     `json:"numFilteredRecords"`
+	ExecutionStart        *time.Time  `json:"executionStart"`
+	ExecutionEnd          *time.Time  `json:"executionEnd"`
+	Options               *RunOptions `json:"options"`
+	TemporalWorkflowID    *string     `json:"temporalWorkflowID"`
+	TemporalRunID         *string     `json:"temporalRunID"`
+	ArtifactsPurged       *bool       `json:"artifactsPurged"`
 }
 
 type RunFilter struct {
-	ID        *int     `json:"id"`
-	SyncID    *int     `json:"syncID"`
-	Status    []string `json:"status"`
-	DateRange []string `json:"dateRange"`
+	ID              *int     `json:"id"`
+	SyncID          *int     `json:"syncID"`
+	Status          []string `json:"status"`
+	DateRange       []string `json:"dateRange"`
+	ArtifactsPurged *bool    `json:"artifactsPurged"`
+
+	// SyncIDs restricts the runs to those of the syncs that a user has permissions for.
+	SyncIDs []int `json:"-"`
 
 	Offset int `json:"offset"`
 	Limit  int `json:"limit"`
@@ -74,6 +90,8 @@ type RunFilter struct {
 type RunService interface {
 	FindRunByID(ctx context.Context, id int) (*Run, error)
 	FindRuns(ctx context.Context, filter RunFilter) ([]*Run, int, error)
+	// FindRunsWithoutSync is like FindRuns but doesn't look up the sync of every run.
+	FindRunsWithoutSync(ctx context.Context, filter RunFilter) ([]*Run, error)
 	CreateRun(ctx context.Context, run *Run) error
 	UpdateRun(ctx context.Context, id int, run *Run) error
 	GetLastRunForSyncID(ctx context.Context, syncID int) (*Run, error)
@@ -92,6 +110,15 @@ func (a *App) UpdateRun(ctx context.Context, id int, upd *RunUpdate) (*Run, erro
 	if v := upd.NumRecords; v != nil {
 		run.Stats.NumRecords = *v
 	}
+	if v := upd.NumInvalidRecords; v != nil {
+		run.Stats.NumInvalidRecords = *v
+	}
+	if v := upd.NumQuarantinedRecords; v != nil {
+		run.Stats.NumQuarantinedRecords = *v
+	}
+	if v := upd.NumFilteredRecords; v != nil {
+		run.Stats.NumFilteredRecords = *v
+	}
 	if v := upd.ExecutionStart; v != nil {
 		run.Stats.ExecutionStart = *v
 	}
@@ -107,6 +134,9 @@ func (a *App) UpdateRun(ctx context.Context, id int, upd *RunUpdate) (*Run, erro
 	if v := upd.TemporalRunID; v != nil {
 		run.TemporalRunID = *v
 	}
+	if v := upd.ArtifactsPurged; v != nil {
+		run.ArtifactsPurged = *v
+	}
 
 	if err := a.DBService.UpdateRun(ctx, id, run); err != nil {
 		return nil, err
@@ -0,0 +1,577 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"cosmos"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+var json = jsoniter.ConfigDefault
+
+const (
+	// Log artifacts are uploaded as a new part whenever this many bytes have been buffered
+	// or when flushInterval has elapsed since the last upload, whichever happens first.
+	partSize      = 1 << 20
+	flushInterval = 5 * time.Second
+
+	// Parts of a log artifact are stored under <artifact>.<attempt>/<partPrefix><timestamp>-<writer>.
+	partPrefix = "part-"
+)
+
+var _ cosmos.ArtifactService = (*ArtifactService)(nil)
+
+// ArtifactService stores artifacts in an S3 compatible object store so that
+// they are available irrespective of the worker which executed the run.
+//
+// Artifacts that are mounted into connector containers are downloaded (and decrypted)
+// to the local filesystem on demand and removed when the artifactory is closed.
+type ArtifactService struct {
+	endpoint        string
+	accessKeyID     string
+	secretAccessKey string
+	bucket          string
+	secure          bool
+
+	// Dir is the local directory in which the artifacts of running runs are written
+	// before they are uploaded to the object store.
+	Dir string
+
+	// HostDir is Dir as seen by the docker daemon. For Docker-in-Docker, this is the path on the host.
+	HostDir string
+
+	// Cipher encrypts the artifacts at rest. Artifacts are not encrypted if it is nil.
+	Cipher *cosmos.Cipher
+
+	client *minio.Client
+}
+
+func NewArtifactService(endpoint, accessKeyID, secretAccessKey, bucket string, secure bool) *ArtifactService {
+	return &ArtifactService{
+		endpoint:        endpoint,
+		accessKeyID:     accessKeyID,
+		secretAccessKey: secretAccessKey,
+		bucket:          bucket,
+		secure:          secure,
+		Dir:             cosmos.ArtifactDir,
+	}
+}
+
+// Open connects to the object store and creates the bucket if it doesn't already exist.
+func (s *ArtifactService) Open() (err error) {
+	s.client, err = minio.New(s.endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(s.accessKeyID, s.secretAccessKey, ""),
+		Secure: s.secure,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	exists, err := s.client.BucketExists(ctx, s.bucket)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return s.client.MakeBucket(ctx, s.bucket, minio.MakeBucketOptions{})
+	}
+
+	return nil
+}
+
+func (s *ArtifactService) Close() error {
+	return nil
+}
+
+func (s *ArtifactService) GetArtifactory(syncID int, executionDate time.Time) (*cosmos.Artifactory, error) {
+	return s.LookupArtifactory(syncID, executionDate), nil
+}
+
+// LookupArtifactory is the same as GetArtifactory since objects are created as they are written.
+func (s *ArtifactService) LookupArtifactory(syncID int, executionDate time.Time) *cosmos.Artifactory {
+	return &cosmos.Artifactory{
+		Path: path.Join(strconv.Itoa(syncID), executionDate.Format(time.RFC3339)),
+	}
+}
+
+func (s *ArtifactService) GetArtifactRef(artifactory *cosmos.Artifactory, id int, attempt int32) (*cosmos.ArtifactLogger, error) {
+	return artifactory.Logger(id, attempt, func() (*cosmos.ArtifactLogger, error) {
+		pw, err := s.newPartWriter(path.Join(artifactory.Path, cosmos.ArtifactFileName(id, attempt)))
+		if err != nil {
+			return nil, err
+		}
+		w, err := s.Cipher.NewWriter(pw)
+		if err != nil {
+			pw.Close()
+			return nil, err
+		}
+		return cosmos.NewArtifactLogger(w, id, attempt), nil
+	})
+}
+
+func (s *ArtifactService) WriteArtifact(artifactory *cosmos.Artifactory, id int, contents interface{}) error {
+	if reflect.ValueOf(contents).IsNil() {
+		return nil
+	}
+
+	b, err := json.Marshal(contents)
+	if err != nil {
+		return err
+	}
+
+	if b, err = s.Cipher.Seal(b); err != nil {
+		return err
+	}
+
+	key := path.Join(artifactory.Path, cosmos.ArtifactNames[id])
+	if _, err := s.client.PutObject(context.Background(), s.bucket, key, bytes.NewReader(b), int64(len(b)), minio.PutObjectOptions{
+		ContentType: "application/json",
+	}); err != nil {
+		return err
+	}
+
+	// Remove any stale local copy so that the next mount sees the new contents.
+	os.Remove(s.localPath(artifactory, id))
+
+	return nil
+}
+
+func (s *ArtifactService) GetArtifactPath(artifactory *cosmos.Artifactory, id int) *string {
+	localPath := s.localPath(artifactory, id)
+
+	if _, err := os.Stat(localPath); os.IsNotExist(err) {
+		data, err := s.GetArtifactData(artifactory, id)
+		if err != nil {
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(localPath), 0700); err != nil {
+			return nil
+		}
+		if err := ioutil.WriteFile(localPath, data, 0600); err != nil {
+			return nil
+		}
+	}
+
+	// For Docker-in-Docker, we have to return the path as it would be on the host.
+	hostPath := strings.TrimPrefix(localPath, s.Dir)
+	hostPath = filepath.Join(s.HostDir, hostPath)
+
+	return &hostPath
+}
+
+func (s *ArtifactService) GetArtifactData(artifactory *cosmos.Artifactory, id int) ([]byte, error) {
+	ctx := context.Background()
+
+	files, err := s.listFiles(ctx, artifactory, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, cosmos.Errorf(cosmos.ENOTFOUND, "Requested artifact does not exist")
+	}
+
+	var data []byte
+	for _, f := range files {
+		b, err := s.readFile(ctx, f, 0)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, b...)
+	}
+
+	return s.Cipher.OpenArtifact(id, data)
+}
+
+func (s *ArtifactService) GetArtifactAttemptData(artifactory *cosmos.Artifactory, id int, attempt int32) ([]byte, error) {
+	ctx := context.Background()
+
+	files, err := s.listFiles(ctx, artifactory, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range files {
+		if f.Attempt == attempt {
+			data, err := s.readFile(ctx, f, 0)
+			if err != nil {
+				return nil, err
+			}
+			return s.Cipher.OpenLines(data)
+		}
+	}
+
+	return nil, cosmos.Errorf(cosmos.ENOTFOUND, "Requested artifact does not exist for attempt %d", attempt)
+}
+
+// GetArtifactDataFrom returns the complete lines of a log artifact starting at the given offset
+// along with the offset just past them. The attempts of a log artifact are read one after the other
+// as if they were a single object. Offsets refer to the stored (possibly encrypted) artifact.
+func (s *ArtifactService) GetArtifactDataFrom(artifactory *cosmos.Artifactory, id int, offset int64) ([]byte, int64, error) {
+	ctx := context.Background()
+
+	files, err := s.listFiles(ctx, artifactory, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(files) == 0 {
+		return nil, 0, cosmos.Errorf(cosmos.ENOTFOUND, "Requested artifact does not exist")
+	}
+
+	var data []byte
+	start := offset
+	for _, f := range files {
+		if start >= f.Size {
+			start -= f.Size
+			continue
+		}
+		b, err := s.readFile(ctx, f, start)
+		if err != nil {
+			return nil, 0, err
+		}
+		data = append(data, b...)
+		start = 0
+	}
+
+	data = data[:bytes.LastIndexByte(data, '\n')+1]
+	next := offset + int64(len(data))
+
+	data, err = s.Cipher.OpenLines(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return data, next, nil
+}
+
+func (s *ArtifactService) ListArtifacts(artifactory *cosmos.Artifactory) ([]*cosmos.ArtifactFile, error) {
+	ctx := context.Background()
+	artifacts := []*cosmos.ArtifactFile{}
+
+	for id := 0; id < cosmos.ArtifactMax; id++ {
+		files, err := s.listFiles(ctx, artifactory, id)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			if cosmos.IsLogArtifact(id) {
+				// Only the first line has to be read to find when the attempt started.
+				// Encrypted lines have to be read in full to be decrypted.
+				if b, err := s.readObject(ctx, f.parts[0].Key, 0, 4095); err == nil {
+					if i := bytes.IndexByte(b, '\n'); i >= 0 {
+						b = b[:i+1]
+					}
+					if line, err := s.Cipher.OpenLines(b); err == nil {
+						if start, ok := cosmos.ParseArtifactLogTime(line); ok {
+							f.Start = &start
+						}
+					}
+				}
+			}
+			artifacts = append(artifacts, &f.ArtifactFile)
+		}
+	}
+
+	return artifacts, nil
+}
+
+func (s *ArtifactService) CloseArtifactory(artifactory *cosmos.Artifactory) {
+	for _, artifact := range artifactory.Loggers() {
+		if err := artifact.Close(); err != nil {
+			log.Printf("artifact upload err: %s", err)
+		}
+	}
+
+	os.RemoveAll(filepath.Join(s.Dir, artifactory.Path))
+}
+
+func (s *ArtifactService) GetArtifactorySize(artifactory *cosmos.Artifactory) (int64, error) {
+	var size int64
+
+	// Listing stops once the context is cancelled, when returning early on an error.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: artifactory.Path + "/", Recursive: true}) {
+		if object.Err != nil {
+			return 0, object.Err
+		}
+		size += object.Size
+	}
+
+	return size, nil
+}
+
+func (s *ArtifactService) DeleteArtifactory(artifactory *cosmos.Artifactory) error {
+	// Cancelling the context stops the retry timers of the requests.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The error channel must be drained for the goroutine which removes the objects to exit.
+	objects := s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: artifactory.Path + "/", Recursive: true})
+	failures := []string{}
+	for err := range s.client.RemoveObjects(ctx, s.bucket, objects, minio.RemoveObjectsOptions{}) {
+		failures = append(failures, fmt.Sprintf("%s: %s", err.ObjectName, err.Err))
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to remove %d objects of %s: %s", len(failures), artifactory.Path, strings.Join(failures, "; "))
+	}
+
+	return os.RemoveAll(filepath.Join(s.Dir, artifactory.Path))
+}
+
+func (s *ArtifactService) getObject(ctx context.Context, key string) ([]byte, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+
+	return ioutil.ReadAll(object)
+}
+
+// readObject returns the bytes of an object in the (inclusive) range [start, end].
+func (s *ArtifactService) readObject(ctx context.Context, key string, start, end int64) ([]byte, error) {
+	opts := minio.GetObjectOptions{}
+	if err := opts.SetRange(start, end); err != nil {
+		return nil, err
+	}
+
+	object, err := s.client.GetObject(ctx, s.bucket, key, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+
+	return ioutil.ReadAll(object)
+}
+
+// artifactFile is a stored artifact. It is made up of a sequence of parts for log artifacts.
+type artifactFile struct {
+	cosmos.ArtifactFile
+	parts []minio.ObjectInfo
+}
+
+// listFiles returns the files of an artifact. The files of a log artifact are ordered by attempt.
+func (s *ArtifactService) listFiles(ctx context.Context, artifactory *cosmos.Artifactory, id int) ([]*artifactFile, error) {
+	name := cosmos.ArtifactNames[id]
+	files := []*artifactFile{}
+
+	if !cosmos.IsLogArtifact(id) {
+		info, err := s.client.StatObject(ctx, s.bucket, path.Join(artifactory.Path, name), minio.StatObjectOptions{})
+		if err != nil {
+			if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+				return files, nil
+			}
+			return nil, err
+		}
+		return append(files, &artifactFile{
+			ArtifactFile: cosmos.ArtifactFile{
+				ID:    id,
+				Name:  name,
+				Size:  info.Size,
+				Start: &info.LastModified,
+				End:   &info.LastModified,
+			},
+			parts: []minio.ObjectInfo{info},
+		}), nil
+	}
+
+	// Parts are listed in lexicographic order, i.e, grouped by attempt and in the order they were written.
+	attempts := map[int32]*artifactFile{}
+	prefix := path.Join(artifactory.Path, name) + "."
+
+	for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if object.Err != nil {
+			return nil, object.Err
+		}
+
+		dir := path.Dir(strings.TrimPrefix(object.Key, prefix))
+		attempt, err := strconv.ParseInt(dir, 10, 32)
+		if err != nil || !strings.HasPrefix(path.Base(object.Key), partPrefix) {
+			continue
+		}
+
+		f, ok := attempts[int32(attempt)]
+		if !ok {
+			f = &artifactFile{ArtifactFile: cosmos.ArtifactFile{ID: id, Name: name, Attempt: int32(attempt)}}
+			attempts[int32(attempt)] = f
+			files = append(files, f)
+		}
+
+		lastModified := object.LastModified
+		if f.Start == nil {
+			f.Start = &lastModified
+		}
+		f.End = &lastModified
+		f.Size += object.Size
+		f.parts = append(f.parts, object)
+	}
+
+	// Older runs have a single sequence of parts under the name of the artifact.
+	if len(files) == 0 {
+		f := &artifactFile{ArtifactFile: cosmos.ArtifactFile{ID: id, Name: name}}
+		for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: path.Join(artifactory.Path, name, partPrefix)}) {
+			if object.Err != nil {
+				return nil, object.Err
+			}
+
+			lastModified := object.LastModified
+			if f.Start == nil {
+				f.Start = &lastModified
+			}
+			f.End = &lastModified
+			f.Size += object.Size
+			f.parts = append(f.parts, object)
+		}
+		if len(f.parts) > 0 {
+			files = append(files, f)
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Attempt < files[j].Attempt
+	})
+
+	return files, nil
+}
+
+// readFile returns the contents of an artifact file starting at the given offset.
+func (s *ArtifactService) readFile(ctx context.Context, f *artifactFile, offset int64) ([]byte, error) {
+	var data []byte
+	start := int64(0)
+
+	for _, part := range f.parts {
+		end := start + part.Size
+		if end > offset {
+			var b []byte
+			var err error
+			if offset > start {
+				b, err = s.readObject(ctx, part.Key, offset-start, part.Size-1)
+			} else {
+				b, err = s.getObject(ctx, part.Key)
+			}
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, b...)
+		}
+		start = end
+	}
+
+	return data, nil
+}
+
+// localPath returns the path at which an artifact is made available on the local filesystem.
+func (s *ArtifactService) localPath(artifactory *cosmos.Artifactory, id int) string {
+	return filepath.Join(s.Dir, artifactory.Path, cosmos.ArtifactNames[id])
+}
+
+// partWriter buffers the log lines written to an artifact and uploads them as parts.
+type partWriter struct {
+	sync.Mutex
+	buf    bytes.Buffer
+	key    string
+	writer string
+	done   chan struct{}
+	wg     sync.WaitGroup
+
+	*ArtifactService
+}
+
+func (s *ArtifactService) newPartWriter(key string) (*partWriter, error) {
+	// Each writer gets a unique ID so that parts uploaded by different workers never collide.
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	w := &partWriter{
+		key:             key,
+		writer:          hex.EncodeToString(b),
+		done:            make(chan struct{}),
+		ArtifactService: s,
+	}
+
+	w.wg.Add(1)
+	go w.flushLoop()
+
+	return w, nil
+}
+
+func (w *partWriter) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+
+	n, _ := w.buf.Write(p)
+	if w.buf.Len() >= partSize {
+		if err := w.flush(); err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+func (w *partWriter) Close() error {
+	close(w.done)
+	w.wg.Wait()
+
+	w.Lock()
+	defer w.Unlock()
+
+	return w.flush()
+}
+
+func (w *partWriter) flushLoop() {
+	defer w.wg.Done()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-time.After(flushInterval):
+			w.Lock()
+			if err := w.flush(); err != nil {
+				log.Printf("artifact upload err: %s", err)
+			}
+			w.Unlock()
+		}
+	}
+}
+
+// flush uploads the buffered data as a new part. The buffer is retained if the upload fails
+// so that the data is uploaded with the next part. Must be called with the lock held.
+func (w *partWriter) flush() error {
+	if w.buf.Len() == 0 {
+		return nil
+	}
+
+	// Parts are named such that listing them lexicographically returns them in the order they were written.
+	key := fmt.Sprintf("%s/%s%020d-%s", w.key, partPrefix, time.Now().UnixNano(), w.writer)
+	if _, err := w.client.PutObject(context.Background(), w.bucket, key, bytes.NewReader(w.buf.Bytes()), int64(w.buf.Len()), minio.PutObjectOptions{
+		ContentType: "text/plain",
+	}); err != nil {
+		return err
+	}
+
+	w.buf.Reset()
+	return nil
+}
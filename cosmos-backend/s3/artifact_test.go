@@ -0,0 +1,175 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"cosmos"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// The tests run against the MinIO (or any other S3 compatible) server given by
+// S3_TEST_ENDPOINT, S3_TEST_ACCESS_KEY_ID and S3_TEST_SECRET_ACCESS_KEY, e.g,
+//
+//	docker run -p 9000:9000 minio/minio server /data
+//	S3_TEST_ENDPOINT=localhost:9000 S3_TEST_ACCESS_KEY_ID=minioadmin S3_TEST_SECRET_ACCESS_KEY=minioadmin go test ./s3
+//
+// They are skipped if the server isn't given. Each test uses a bucket of its own.
+func newTestArtifactService(t *testing.T) *ArtifactService {
+	t.Helper()
+
+	endpoint := os.Getenv("S3_TEST_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("S3_TEST_ENDPOINT is not set")
+	}
+
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	bucket := "cosmos-test-" + hex.EncodeToString(b)
+
+	s := NewArtifactService(endpoint, os.Getenv("S3_TEST_ACCESS_KEY_ID"), os.Getenv("S3_TEST_SECRET_ACCESS_KEY"), bucket, false)
+	s.Dir = t.TempDir()
+	s.HostDir = s.Dir
+	if err := s.Open(); err != nil {
+		t.Fatalf("cannot open artifact service: %s", err)
+	}
+
+	t.Cleanup(func() {
+		ctx := context.Background()
+		objects := s.client.ListObjects(ctx, bucket, minio.ListObjectsOptions{Recursive: true})
+		for range s.client.RemoveObjects(ctx, bucket, objects, minio.RemoveObjectsOptions{}) {
+		}
+		s.client.RemoveBucket(ctx, bucket)
+	})
+
+	return s
+}
+
+func TestArtifactService(t *testing.T) {
+	s := newTestArtifactService(t)
+
+	artifactory, err := s.GetArtifactory(1, time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Regular artifacts are stored as a single object.
+	if err := s.WriteArtifact(artifactory, cosmos.ArtifactBeforeState, &struct {
+		Cursor int `json:"cursor"`
+	}{1}); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := s.GetArtifactData(artifactory, cosmos.ArtifactBeforeState); err != nil {
+		t.Fatal(err)
+	} else if got := string(data); got != `{"cursor":1}` {
+		t.Fatalf("unexpected artifact data: %s", got)
+	}
+
+	// Log artifacts are uploaded in parts and are stored separately for every attempt.
+	for attempt := int32(1); attempt <= 2; attempt++ {
+		logger, err := s.GetArtifactRef(artifactory, cosmos.ArtifactWorker, attempt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		logger.Println("attempt started")
+	}
+	s.CloseArtifactory(artifactory)
+
+	if data, err := s.GetArtifactAttemptData(artifactory, cosmos.ArtifactWorker, 2); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Contains(data, []byte("attempt started")) {
+		t.Fatalf("unexpected log artifact data: %s", data)
+	}
+	if data, err := s.GetArtifactData(artifactory, cosmos.ArtifactWorker); err != nil {
+		t.Fatal(err)
+	} else if n := bytes.Count(data, []byte("\n")); n != 2 {
+		t.Fatalf("expected 2 lines in the log artifact, got %d", n)
+	}
+
+	if files, err := s.ListArtifacts(artifactory); err != nil {
+		t.Fatal(err)
+	} else if len(files) != 3 {
+		t.Fatalf("expected 3 artifact files, got %d", len(files))
+	}
+
+	// Artifacts which are mounted into connectors are downloaded.
+	if path := s.GetArtifactPath(artifactory, cosmos.ArtifactBeforeState); path == nil {
+		t.Fatal("expected the artifact to be downloaded")
+	} else if _, err := os.Stat(*path); err != nil {
+		t.Fatal(err)
+	}
+
+	if size, err := s.GetArtifactorySize(artifactory); err != nil {
+		t.Fatal(err)
+	} else if size == 0 {
+		t.Fatal("expected the artifactory to take up space")
+	}
+
+	if err := s.DeleteArtifactory(artifactory); err != nil {
+		t.Fatal(err)
+	}
+	if size, err := s.GetArtifactorySize(artifactory); err != nil {
+		t.Fatal(err)
+	} else if size != 0 {
+		t.Fatalf("expected the artifactory to be empty after deletion, got %d bytes", size)
+	}
+	if _, err := s.GetArtifactData(artifactory, cosmos.ArtifactBeforeState); err == nil {
+		t.Fatal("expected the artifact to be deleted")
+	}
+}
+
+// Objects are removed in batches of a thousand. The goroutines which list and remove
+// them must exit once all the batches have been removed.
+func TestArtifactService_DeleteArtifactory(t *testing.T) {
+	s := newTestArtifactService(t)
+	artifactory := s.LookupArtifactory(2, time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC))
+
+	ctx := context.Background()
+	for i := 0; i < 1200; i++ {
+		key := artifactory.Path + "/worker.1/" + partPrefix + hex.EncodeToString([]byte{byte(i >> 8), byte(i)})
+		if _, err := s.client.PutObject(ctx, s.bucket, key, bytes.NewReader([]byte("x\n")), 2, minio.PutObjectOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.DeleteArtifactory(artifactory); err != nil {
+		t.Fatal(err)
+	}
+
+	if size, err := s.GetArtifactorySize(artifactory); err != nil {
+		t.Fatal(err)
+	} else if size != 0 {
+		t.Fatalf("expected the artifactory to be empty after deletion, got %d bytes", size)
+	}
+
+	// Goroutines exit shortly after their context is cancelled.
+	deadline := time.Now().Add(time.Second)
+	for clientGoroutines() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := clientGoroutines(); n != 0 {
+		t.Fatalf("expected no goroutines of the client to be left after deletion, got %d", n)
+	}
+}
+
+// clientGoroutines counts the goroutines which are running code of the client, e.g, to list or remove objects.
+// Goroutines of idle HTTP connections are not counted.
+func clientGoroutines() int {
+	buf := make([]byte, 1<<20)
+	n := 0
+	for _, g := range strings.Split(string(buf[:runtime.Stack(buf, true)]), "\n\n") {
+		if strings.Contains(g, "github.com/minio/minio-go/v7.") {
+			n++
+		}
+	}
+	return n
+}
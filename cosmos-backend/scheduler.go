@@ -1,5 +1,19 @@
 package cosmos
 
+import "context"
+
 type SchedulerService interface {
-	Schedule(syncID *int, runOptions *RunOptions) error
+	Schedule(ctx context.Context, syncID *int, runOptions *RunOptions) error
+}
+
+// Schedule schedules a run of a sync right away on behalf of the user, e.g, when "Sync now" is clicked.
+func (a *App) Schedule(ctx context.Context, syncID int, runOptions *RunOptions) error {
+	if err := a.SchedulerService.Schedule(ctx, &syncID, runOptions); err != nil {
+		return err
+	}
+
+	options := struct {
+		Options *RunOptions `json:"options"`
+	}{runOptions}
+	return a.audit(ctx, AuditActionSyncNow, AuditEntitySync, syncID, nil, &options)
 }
@@ -3,15 +3,23 @@ package scheduler
 import (
 	"context"
 	"cosmos"
+	"cosmos/opentelemetry"
 	"errors"
 	"log"
 	"runtime/debug"
 	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var _ cosmos.SchedulerService = (*Scheduler)(nil)
 
+var tracer = otel.Tracer("cosmos/scheduler")
+
 type Scheduler struct {
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -56,14 +64,17 @@ func (s *Scheduler) SchedulerLoop(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-time.After(3 * time.Second):
-			s.Schedule(nil, &cosmos.RunOptions{})
+			s.Schedule(ctx, nil, &cosmos.RunOptions{})
 		}
 	}
 }
 
-func (s *Scheduler) Schedule(syncID *int, runOptions *cosmos.RunOptions) error {
+func (s *Scheduler) Schedule(ctx context.Context, syncID *int, runOptions *cosmos.RunOptions) (err error) {
 	defer recoverFromPanic()
 
+	span := newScheduleSpan(ctx, syncID)
+	defer func() { span.end(err) }()
+
 	s.Lock()
 	defer s.Unlock()
 
@@ -94,15 +105,66 @@ func (s *Scheduler) Schedule(syncID *int, runOptions *cosmos.RunOptions) error {
 			continue
 		}
 
-		run = &cosmos.Run{SyncID: sync.ID, ExecutionDate: time.Now(), Options: *runOptions}
-		if err := s.App.CreateRun(ctx, run); err != nil {
+		// The run is created in the span of this call, and keeps its trace context so
+		// that the worker can continue the trace when it starts the run.
+		runCtx := span.context(ctx)
+		run = &cosmos.Run{SyncID: sync.ID, ExecutionDate: time.Now(), Options: *runOptions, TraceParent: opentelemetry.TraceParent(runCtx)}
+		if err := s.App.CreateRun(runCtx, run); err != nil {
 			log.Printf("scheduler err: %s", err)
+			continue
 		}
+		span.scheduled++
 	}
 
 	return nil
 }
 
+// scheduleSpan is the span of a call to Schedule. The scheduler loop calls Schedule every few seconds and
+// mostly has nothing to do, so the span of its calls is only started once a run is scheduled or the call
+// fails. Calls on behalf of the user are always traced, as children of the span of the request.
+type scheduleSpan struct {
+	parent    context.Context
+	start     time.Time
+	syncID    *int
+	scheduled int
+	span      trace.Span
+}
+
+func newScheduleSpan(parent context.Context, syncID *int) *scheduleSpan {
+	span := &scheduleSpan{parent: parent, start: time.Now(), syncID: syncID}
+	if syncID != nil {
+		span.context(parent)
+	}
+	return span
+}
+
+// context returns ctx with the span as its current span, starting the span if it hasn't been started yet.
+func (s *scheduleSpan) context(ctx context.Context) context.Context {
+	if s.span == nil {
+		attrs := []attribute.KeyValue{}
+		if s.syncID != nil {
+			attrs = append(attrs, attribute.Int("sync_id", *s.syncID))
+		}
+		_, s.span = tracer.Start(s.parent, "Scheduler.Schedule", trace.WithTimestamp(s.start), trace.WithAttributes(attrs...))
+	}
+	return trace.ContextWithSpan(ctx, s.span)
+}
+
+// end ends the span (if it was started or the call failed) with the error (if any) that the call failed with.
+func (s *scheduleSpan) end(err error) {
+	if s.span == nil && err == nil {
+		return
+	}
+	s.context(s.parent)
+
+	s.span.SetAttributes(attribute.Int("scheduled_runs", s.scheduled))
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
+
 func okToSchedule(sync *cosmos.Sync, run *cosmos.Run, force bool) (bool, error) {
 	if !sync.Enabled && !force {
 		return false, cosmos.Errorf(cosmos.ECONFLICT, "Not enabled")
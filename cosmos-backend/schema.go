@@ -0,0 +1,166 @@
+package cosmos
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/iancoleman/orderedmap"
+)
+
+// Schema change policies decide what happens to a sync when the catalog of its source endpoint changes.
+const (
+	SchemaChangePolicyIgnore    = "ignore"
+	SchemaChangePolicyPropagate = "propagate"
+	SchemaChangePolicyPause     = "pause"
+)
+
+// Schema change types.
+const (
+	SchemaChangeStreamAdded   = "stream_added"
+	SchemaChangeStreamRemoved = "stream_removed"
+	SchemaChangeFieldAdded    = "field_added"
+	SchemaChangeFieldRemoved  = "field_removed"
+	SchemaChangeFieldRetyped  = "field_retyped"
+)
+
+// SchemaChange represents a difference between the catalog that a sync is configured
+// for and the catalog that was most recently discovered for its source endpoint.
+type SchemaChange struct {
+	Type     string `json:"type"`
+	Stream   string `json:"stream"`
+	Field    string `json:"field,omitempty"`
+	OldType  string `json:"oldType,omitempty"`
+	NewType  string `json:"newType,omitempty"`
+	Breaking bool   `json:"breaking"`
+}
+
+// HasBreakingSchemaChanges returns true if any of the schema changes breaks the sync.
+func HasBreakingSchemaChanges(changes []*SchemaChange) bool {
+	for _, c := range changes {
+		if c.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// DiffCatalog compares a newly discovered catalog with the configured catalog of the sync.
+//
+// Added streams and fields are non-breaking changes. Removed streams and fields and fields
+// whose type has changed are breaking changes. Streams that the user has not selected and
+// fields that the user has excluded are not reported.
+func (s *Sync) DiffCatalog(discovered *Catalog) []*SchemaChange {
+	changes := []*SchemaChange{}
+
+	if s.ConfiguredCatalog.ConfiguredCatalog == nil || discovered == nil {
+		return changes
+	}
+
+	known := map[string]*FormFieldCatalog{}
+	for _, field := range s.Config.Catalog {
+		known[field.Stream.Key()] = field
+	}
+
+	discoveredStreams := map[string]*Stream{}
+	for i, stream := range discovered.Streams {
+		discoveredStreams[stream.Key()] = &discovered.Streams[i]
+		if _, ok := known[stream.Key()]; !ok {
+			changes = append(changes, &SchemaChange{Type: SchemaChangeStreamAdded, Stream: stream.Key()})
+		}
+	}
+
+	for _, configured := range s.ConfiguredCatalog.ConfiguredCatalog.Streams {
+		key := configured.Stream.Key()
+
+		stream, ok := discoveredStreams[key]
+		if !ok {
+			changes = append(changes, &SchemaChange{Type: SchemaChangeStreamRemoved, Stream: key, Breaking: true})
+			continue
+		}
+
+		var excluded []string
+		if field, ok := known[key]; ok {
+			excluded = field.ExcludedFields()
+		}
+
+		oldTypes := fieldTypes(&configured.Stream)
+		newTypes := fieldTypes(stream)
+
+		for _, name := range sortedKeys(newTypes) {
+			if _, ok := oldTypes[name]; !ok && !contains(excluded, name) {
+				changes = append(changes, &SchemaChange{
+					Type:    SchemaChangeFieldAdded,
+					Stream:  key,
+					Field:   name,
+					NewType: newTypes[name],
+				})
+			}
+		}
+
+		for _, name := range sortedKeys(oldTypes) {
+			newType, ok := newTypes[name]
+			if !ok {
+				changes = append(changes, &SchemaChange{
+					Type:     SchemaChangeFieldRemoved,
+					Stream:   key,
+					Field:    name,
+					OldType:  oldTypes[name],
+					Breaking: true,
+				})
+			} else if newType != oldTypes[name] {
+				changes = append(changes, &SchemaChange{
+					Type:     SchemaChangeFieldRetyped,
+					Stream:   key,
+					Field:    name,
+					OldType:  oldTypes[name],
+					NewType:  newType,
+					Breaking: true,
+				})
+			}
+		}
+	}
+
+	return changes
+}
+
+// fieldTypes returns the JSON schema types of the top-level fields of a stream.
+// Nullability is ignored, i.e, ["null", "string"] is the same as "string".
+func fieldTypes(stream *Stream) map[string]string {
+	result := map[string]string{}
+
+	v, _ := stream.JSONSchema.Get("properties")
+	properties, ok := v.(orderedmap.OrderedMap)
+	if !ok {
+		return result
+	}
+
+	for _, name := range properties.Keys() {
+		v, _ := properties.Get(name)
+		property, _ := v.(orderedmap.OrderedMap)
+
+		types := []string{}
+		switch t, _ := property.Get("type"); t := t.(type) {
+		case string:
+			types = append(types, t)
+		case []interface{}:
+			for _, x := range t {
+				if s, ok := x.(string); ok && s != "null" {
+					types = append(types, s)
+				}
+			}
+		}
+		sort.Strings(types)
+		result[name] = strings.Join(types, ",")
+	}
+
+	return result
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,129 @@
+package cosmos
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/iancoleman/orderedmap"
+)
+
+// testStream returns a stream whose JSON schema has the given properties, e.g, `{"id": {"type": "integer"}}`.
+func testStream(t *testing.T, name, properties string) Stream {
+	t.Helper()
+
+	schema := orderedmap.New()
+	if err := schema.UnmarshalJSON([]byte(`{"type": "object", "properties": ` + properties + `}`)); err != nil {
+		t.Fatal(err)
+	}
+	return Stream{Name: name, JSONSchema: *schema}
+}
+
+func TestDiffCatalog(t *testing.T) {
+	const users = `{"id": {"type": "integer"}, "email": {"type": ["null", "string"]}}`
+
+	tests := []struct {
+		name       string
+		configured []Stream
+		selected   map[string][]string
+		discovered []Stream
+		want       []*SchemaChange
+	}{
+		{
+			name:       "no changes",
+			configured: []Stream{testStream(t, "users", users)},
+			discovered: []Stream{testStream(t, "users", users)},
+			want:       []*SchemaChange{},
+		},
+		{
+			name:       "nullability is ignored",
+			configured: []Stream{testStream(t, "users", users)},
+			discovered: []Stream{testStream(t, "users", `{"id": {"type": ["integer", "null"]}, "email": {"type": "string"}}`)},
+			want:       []*SchemaChange{},
+		},
+		{
+			name:       "stream added",
+			configured: []Stream{testStream(t, "users", users)},
+			discovered: []Stream{testStream(t, "users", users), testStream(t, "orders", `{"id": {"type": "integer"}}`)},
+			want: []*SchemaChange{
+				{Type: SchemaChangeStreamAdded, Stream: "orders"},
+			},
+		},
+		{
+			name:       "stream removed",
+			configured: []Stream{testStream(t, "users", users), testStream(t, "orders", `{"id": {"type": "integer"}}`)},
+			discovered: []Stream{testStream(t, "users", users)},
+			want: []*SchemaChange{
+				{Type: SchemaChangeStreamRemoved, Stream: "orders", Breaking: true},
+			},
+		},
+		{
+			name:       "field added",
+			configured: []Stream{testStream(t, "users", users)},
+			discovered: []Stream{testStream(t, "users", `{"id": {"type": "integer"}, "email": {"type": "string"}, "name": {"type": "string"}}`)},
+			want: []*SchemaChange{
+				{Type: SchemaChangeFieldAdded, Stream: "users", Field: "name", NewType: "string"},
+			},
+		},
+		{
+			name:       "excluded field added",
+			configured: []Stream{testStream(t, "users", `{"id": {"type": "integer"}}`)},
+			selected:   map[string][]string{"users": {"id"}},
+			discovered: []Stream{testStream(t, "users", users)},
+			want:       []*SchemaChange{},
+		},
+		{
+			name:       "field removed",
+			configured: []Stream{testStream(t, "users", users)},
+			discovered: []Stream{testStream(t, "users", `{"id": {"type": "integer"}}`)},
+			want: []*SchemaChange{
+				{Type: SchemaChangeFieldRemoved, Stream: "users", Field: "email", OldType: "string", Breaking: true},
+			},
+		},
+		{
+			name:       "field retyped",
+			configured: []Stream{testStream(t, "users", users)},
+			discovered: []Stream{testStream(t, "users", `{"id": {"type": "string"}, "email": {"type": "string"}}`)},
+			want: []*SchemaChange{
+				{Type: SchemaChangeFieldRetyped, Stream: "users", Field: "id", OldType: "integer", NewType: "string", Breaking: true},
+			},
+		},
+		{
+			name:       "multiple changes",
+			configured: []Stream{testStream(t, "users", users)},
+			discovered: []Stream{testStream(t, "users", `{"id": {"type": "number"}, "name": {"type": "string"}}`)},
+			want: []*SchemaChange{
+				{Type: SchemaChangeFieldAdded, Stream: "users", Field: "name", NewType: "string"},
+				{Type: SchemaChangeFieldRemoved, Stream: "users", Field: "email", OldType: "string", Breaking: true},
+				{Type: SchemaChangeFieldRetyped, Stream: "users", Field: "id", OldType: "integer", NewType: "number", Breaking: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sync := &Sync{ConfiguredCatalog: Message{ConfiguredCatalog: &ConfiguredCatalog{}}}
+			for _, stream := range tt.configured {
+				sync.ConfiguredCatalog.ConfiguredCatalog.Streams = append(sync.ConfiguredCatalog.ConfiguredCatalog.Streams, ConfiguredStream{Stream: stream})
+				field := &FormFieldCatalog{Stream: stream, StreamName: stream.Name, IsStreamSelected: true}
+				if selected, ok := tt.selected[stream.Name]; ok {
+					field.Fields = []string{"id", "email"}
+					field.SelectedFields = selected
+				}
+				sync.Config.Catalog = append(sync.Config.Catalog, field)
+			}
+
+			changes := sync.DiffCatalog(&Catalog{Streams: tt.discovered})
+			if !reflect.DeepEqual(changes, tt.want) {
+				t.Fatalf("unexpected changes: %s, want: %s", formatSchemaChanges(changes), formatSchemaChanges(tt.want))
+			}
+			if got, want := HasBreakingSchemaChanges(changes), HasBreakingSchemaChanges(tt.want); got != want {
+				t.Fatalf("unexpected breaking: %t, want: %t", got, want)
+			}
+		})
+	}
+}
+
+func formatSchemaChanges(changes []*SchemaChange) string {
+	b, _ := json.Marshal(changes)
+	return string(b)
+}
@@ -0,0 +1,192 @@
+package cosmos
+
+import (
+	"context"
+	"regexp"
+)
+
+// secretRefPattern matches references to secrets stored outside of cosmos, e.g, "${env:PG_PASSWORD}"
+// or "${file:pg}". The whole value of a field must be a reference.
+var secretRefPattern = regexp.MustCompile(`^\$\{([a-z]+):(.+)\}$`)
+
+// SecretResolver resolves references to secrets which are stored outside of cosmos.
+type SecretResolver interface {
+	// ResolveSecret returns the secret with the given key, e.g, the name of an environment variable,
+	// from the store identified by scheme, e.g, "env".
+	ResolveSecret(ctx context.Context, scheme, key string) (string, error)
+}
+
+// ParseSecretRef returns the scheme and key of a reference to a secret.
+// ok is false if the value is not a reference.
+func ParseSecretRef(v interface{}) (scheme, key string, ok bool) {
+	s, isString := v.(string)
+	if !isString {
+		return "", "", false
+	}
+	m := secretRefPattern.FindStringSubmatch(s)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+// ConnectorConfig builds the config of a connector from the config form of an endpoint.
+// References to external secrets in secret fields are resolved and encrypted secrets are decrypted.
+// Endpoint.Validate rejects references in other fields, since their values are returned by the API.
+// The config is only meant to be passed to the connector. It must never be stored or returned.
+func (a *App) ConnectorConfig(ctx context.Context, form *Form) (map[string]interface{}, error) {
+	resolved := *form
+	resolved.Spec = make([]*FormFieldSpec, len(form.Spec))
+	for i, field := range form.Spec {
+		field := *field
+		if scheme, key, ok := ParseSecretRef(field.Value); ok && field.Secret {
+			secret, err := a.ResolveSecret(ctx, scheme, key)
+			if err != nil {
+				return nil, err
+			}
+			field.Value = secret
+		}
+		resolved.Spec[i] = &field
+	}
+
+	return resolved.ToSpec(a.SecretCipher)
+}
+
+// SecretPaths returns the paths (in the connector config) of all the fields of a spec form which
+// are marked as secret.
+func (f *Form) SecretPaths() [][]string {
+	paths := [][]string{}
+
+	for _, field := range f.Spec {
+		if !field.Secret {
+			continue
+		}
+		path := []string{}
+		for _, p := range field.Path {
+			if !OneOfPattern.MatchString(p) {
+				path = append(path, p)
+			}
+		}
+		paths = append(paths, path)
+	}
+
+	return paths
+}
+
+// Redacted returns a copy of the form with the values of its secret fields replaced with RedactedValue.
+func (f *Form) Redacted() *Form {
+	form := *f
+	form.Spec = make([]*FormFieldSpec, len(f.Spec))
+	for i, field := range f.Spec {
+		field := *field
+		if field.Secret && field.Value != nil {
+			field.Value = RedactedValue
+		}
+		form.Spec[i] = &field
+	}
+	return &form
+}
+
+// Masked returns a copy of the form without the values of its secret fields, so that it can be returned
+// by the API. Only whether a secret is set is exposed. References to external secrets are kept as is.
+func (f *Form) Masked() *Form {
+	form := *f
+	form.Spec = make([]*FormFieldSpec, len(f.Spec))
+	for i, field := range f.Spec {
+		field := *field
+		if field.Secret {
+			field.IsSet = field.IsSet || field.Value != nil
+			if _, _, ok := ParseSecretRef(field.Value); !ok {
+				field.Value = nil
+			}
+		}
+		form.Spec[i] = &field
+	}
+	return &form
+}
+
+// KeepSecrets sets the secret fields which have been left out or sent back masked, i.e, as returned by the API,
+// to their values in the current form, so that the secrets of an endpoint don't have to be sent again on every
+// update. A secret field which is explicitly set to an empty string or null is cleared.
+func (f *Form) KeepSecrets(current *Form) {
+	for _, c := range current.Spec {
+		if !c.Secret {
+			continue
+		}
+		if idx := f.fieldIndex(c.Path); idx >= 0 {
+			field := f.Spec[idx]
+			if field.Value == RedactedValue || (field.Value == nil && field.IsSet) {
+				field.Value = c.Value
+			}
+			continue
+		}
+
+		// The field has been left out. The field on which it depends must be looked up again since
+		// its index may differ.
+		keep := *c
+		if c.DependsOnIdx != nil {
+			idx := f.fieldIndex(current.Spec[*c.DependsOnIdx].Path)
+			if idx < 0 {
+				continue
+			}
+			keep.DependsOnIdx = &idx
+		}
+		f.Spec = append(f.Spec, &keep)
+	}
+
+	for _, field := range f.Spec {
+		field.IsSet = false
+	}
+}
+
+// fieldIndex returns the index of the field with the given path in the spec, or -1 if there is none.
+func (f *Form) fieldIndex(path []string) int {
+	for i, field := range f.Spec {
+		if testEq(field.Path, path) {
+			return i
+		}
+	}
+	return -1
+}
+
+// MaskSecrets replaces the values at the given paths in a connector config with RedactedValue.
+func MaskSecrets(config map[string]interface{}, paths [][]string) {
+	for _, path := range paths {
+		if len(path) == 0 {
+			continue
+		}
+
+		m := config
+		for _, p := range path[:len(path)-1] {
+			child, ok := m[p].(map[string]interface{})
+			if !ok {
+				m = nil
+				break
+			}
+			m = child
+		}
+
+		if v, ok := m[path[len(path)-1]]; ok && v != nil {
+			m[path[len(path)-1]] = RedactedValue
+		}
+	}
+}
+
+// RedactedConfig returns the connector config of an endpoint with all of its secrets masked so that
+// it is safe to display to the user. The secrets are found using the spec of the endpoint's connector
+// as well as the endpoint's config form in case they differ.
+func (a *App) RedactedConfig(ctx context.Context, endpoint *Endpoint) map[string]interface{} {
+	// The secrets of the form are redacted before building the config, so nothing needs to be decrypted.
+	config, _ := endpoint.Config.Redacted().ToSpec(nil)
+	a.RedactConfig(ctx, endpoint, config)
+	return config
+}
+
+// RedactConfig masks the secrets in a connector config of an endpoint.
+func (a *App) RedactConfig(ctx context.Context, endpoint *Endpoint, config map[string]interface{}) {
+	if endpoint.Connector != nil && endpoint.Connector.Spec.Spec != nil {
+		spec := a.MessageToForm(ctx, &endpoint.Connector.Spec, nil)
+		MaskSecrets(config, spec.SecretPaths())
+	}
+	MaskSecrets(config, endpoint.Config.SecretPaths())
+}
@@ -0,0 +1,111 @@
+package secrets
+
+import (
+	"context"
+	"cosmos"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var _ cosmos.SecretResolver = (*SecretResolver)(nil)
+
+// SecretResolver resolves references to secrets in environment variables ("${env:NAME}")
+// and in files ("${file:name}"), e.g, secrets mounted by an orchestrator. Only the
+// environment variables and files which have been allowed can be referenced.
+type SecretResolver struct {
+	// Dir is the directory that secret files must be in. Files can't be referenced if it is empty.
+	Dir string
+
+	// EnvPrefix is the prefix of the environment variables which can be referenced.
+	// No environment variable is allowed by the prefix if it is empty.
+	EnvPrefix string
+
+	// EnvAllowlist contains further environment variables which can be referenced.
+	EnvAllowlist []string
+}
+
+func NewSecretResolver() *SecretResolver {
+	return &SecretResolver{}
+}
+
+func (r *SecretResolver) ResolveSecret(ctx context.Context, scheme, key string) (string, error) {
+	switch scheme {
+	case "env":
+		return r.resolveEnv(key)
+	case "file":
+		return r.resolveFile(key)
+	default:
+		return "", cosmos.Errorf(cosmos.EINVALID, "Unknown secret reference '%s'. References must be of the form ${env:NAME} or ${file:name}", scheme)
+	}
+}
+
+func (r *SecretResolver) resolveEnv(name string) (string, error) {
+	if !r.isAllowedEnv(name) {
+		return "", cosmos.Errorf(cosmos.EINVALID, "Environment variable %s referenced in the config is not allowed. Only variables starting with %q or in the allowlist can be referenced", name, r.EnvPrefix)
+	}
+
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", cosmos.Errorf(cosmos.EINVALID, "Environment variable %s referenced in the config is not set", name)
+	}
+	return value, nil
+}
+
+func (r *SecretResolver) isAllowedEnv(name string) bool {
+	if r.EnvPrefix != "" && strings.HasPrefix(name, r.EnvPrefix) && len(name) > len(r.EnvPrefix) {
+		return true
+	}
+	for _, allowed := range r.EnvAllowlist {
+		if name == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveFile reads a file in Dir. The file is given relative to Dir, or as an absolute path inside of it.
+func (r *SecretResolver) resolveFile(name string) (string, error) {
+	if r.Dir == "" {
+		return "", cosmos.Errorf(cosmos.EINVALID, "Secret file %s referenced in the config is not allowed since no secrets directory is configured", name)
+	}
+
+	dir := filepath.Clean(r.Dir)
+	path := filepath.Clean(name)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	if !isInDir(dir, path) {
+		return "", cosmos.Errorf(cosmos.EINVALID, "Secret file %s referenced in the config must be in %s", name, r.Dir)
+	}
+
+	// Symbolic links must not lead out of the directory either.
+	resolvedDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", err
+	}
+	resolvedPath, err := filepath.EvalSymlinks(path)
+	if os.IsNotExist(err) {
+		return "", cosmos.Errorf(cosmos.EINVALID, "Secret file %s referenced in the config does not exist", name)
+	} else if err != nil {
+		return "", err
+	}
+	if !isInDir(resolvedDir, resolvedPath) {
+		return "", cosmos.Errorf(cosmos.EINVALID, "Secret file %s referenced in the config must be in %s", name, r.Dir)
+	}
+
+	b, err := ioutil.ReadFile(resolvedPath)
+	if err != nil {
+		return "", err
+	}
+
+	// Files written by hand or by tools such as "echo" usually end with a newline, which isn't part of the secret.
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// isInDir returns true if the cleaned path is below the cleaned directory.
+func isInDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
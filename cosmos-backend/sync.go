@@ -13,8 +13,17 @@ const (
 	NamespaceDefinitionCustom      = "custom"
 )
 
+// Record validation modes.
+const (
+	ValidationModeOff        = "off"
+	ValidationModeWarn       = "warn"
+	ValidationModeQuarantine = "quarantine"
+	ValidationModeFail       = "fail"
+)
+
 type Sync struct {
 	ID                    int                    `json:"id"`
+	WorkspaceID           int                    `json:"workspaceID"`
 	Name                  string                 `json:"name"`
 	SourceEndpointID      int                    `json:"sourceEndpointID"`
 	DestinationEndpointID int                    `json:"destinationEndpointID"`
@@ -24,6 +33,9 @@ type Sync struct {
 	NamespaceDefinition   string                 `json:"namespaceDefinition"`
 	NamespaceFormat       string                 `json:"namespaceFormat"`
 	StreamPrefix          string                 `json:"streamPrefix"`
+	ValidationMode        string                 `json:"validationMode"`
+	SchemaChangePolicy    string                 `json:"schemaChangePolicy"`
+	SchemaChanges         []*SchemaChange        `json:"schemaChanges"`
 	State                 map[string]interface{} `json:"state"`
 	Config                Form                   `json:"config"`
 	ConfiguredCatalog     Message                `json:"configuredCatalog"`
@@ -46,6 +58,14 @@ func (s *Sync) Validate() error {
 		return Errorf(EINVALID, "Schedule interval must be greater than or equal to 0")
 	} else if err := s.hasValidNamespaceDefinition(); err != nil {
 		return Errorf(EINVALID, err.Error())
+	} else if !s.hasValidValidationMode() {
+		return Errorf(EINVALID, "Invalid validation mode: %s", s.ValidationMode)
+	} else if !s.hasValidSchemaChangePolicy() {
+		return Errorf(EINVALID, "Invalid schema change policy: %s", s.SchemaChangePolicy)
+	} else if err := s.hasValidFieldSelection(); err != nil {
+		return Errorf(EINVALID, err.Error())
+	} else if err := s.hasValidFieldPolicies(); err != nil {
+		return Errorf(EINVALID, err.Error())
 	}
 	return nil
 }
@@ -63,6 +83,71 @@ func (s *Sync) hasValidNamespaceDefinition() error {
 	return nil
 }
 
+func (s *Sync) hasValidValidationMode() bool {
+	switch s.ValidationMode {
+	case ValidationModeOff, ValidationModeWarn, ValidationModeQuarantine, ValidationModeFail:
+		return true
+	}
+	return false
+}
+
+func (s *Sync) hasValidSchemaChangePolicy() bool {
+	switch s.SchemaChangePolicy {
+	case SchemaChangePolicyIgnore, SchemaChangePolicyPropagate, SchemaChangePolicyPause:
+		return true
+	}
+	return false
+}
+
+func (s *Sync) hasValidFieldSelection() error {
+	for _, field := range s.Config.Catalog {
+		if !field.IsStreamSelected {
+			continue
+		}
+		excluded := field.ExcludedFields()
+		if field.SelectedFields != nil && len(excluded) == len(field.Fields) {
+			return fmt.Errorf("Stream %s: at least one field must be selected", field.StreamName)
+		}
+		// The cursor field and the primary key must always be replicated.
+		if field.usesCursorField() && contains(excluded, field.SelectedCursorField[0]) {
+			return fmt.Errorf("Stream %s: the cursor field cannot be excluded", field.StreamName)
+		}
+		if field.usesPrimaryKey() {
+			for _, key := range field.SelectedPrimaryKey {
+				if len(key) != 0 && contains(excluded, key[0]) {
+					return fmt.Errorf("Stream %s: the primary key cannot be excluded", field.StreamName)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Sync) hasValidFieldPolicies() error {
+	for _, field := range s.Config.Catalog {
+		for _, policy := range field.FieldPolicies {
+			if err := policy.Validate(); err != nil {
+				return fmt.Errorf("Stream %s: %s", field.StreamName, err)
+			}
+			if policy.Action != FieldPolicyDrop {
+				continue
+			}
+			// Dropping the cursor field or the primary key would break incremental and deduped syncs.
+			if field.usesCursorField() && testEq(policy.Path, field.SelectedCursorField) {
+				return fmt.Errorf("Stream %s: the cursor field cannot be dropped", field.StreamName)
+			}
+			if field.usesPrimaryKey() {
+				for _, key := range field.SelectedPrimaryKey {
+					if testEq(policy.Path, key) {
+						return fmt.Errorf("Stream %s: the primary key cannot be dropped", field.StreamName)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
 func (s *Sync) NamespaceMapper(obj interface{}) {
 	var streamName *string
 	var namespace **string
@@ -102,12 +187,21 @@ type SyncUpdate struct {
 	NamespaceDefinition *string                 `json:"namespaceDefinition"`
 	NamespaceFormat     *string                 `json:"namespaceFormat"`
 	StreamPrefix        *string                 `json:"streamPrefix"`
+	ValidationMode      *string                 `json:"validationMode"`
+	SchemaChangePolicy  *string                 `json:"schemaChangePolicy"`
 	State               *map[string]interface{} `json:"state"`
+
+	// SchemaChanges can only be set by cosmos when the source endpoint is rediscovered.
+	SchemaChanges *[]*SchemaChange `json:"-"`
 }
 
 type SyncFilter struct {
-	ID   *int    `json:"id"`
-	Name *string `json:"name"`
+	ID               *int    `json:"id"`
+	Name             *string `json:"name"`
+	SourceEndpointID *int    `json:"sourceEndpointID"`
+
+	// IDs restricts the syncs to those that a user has permissions for.
+	IDs []int `json:"-"`
 
 	Offset int `json:"offset"`
 	Limit  int `json:"limit"`
@@ -122,11 +216,39 @@ type SyncService interface {
 }
 
 func (a *App) CreateSync(ctx context.Context, sync *Sync) error {
+	// Records are not validated unless the user explicitly asks for it.
+	if sync.ValidationMode == "" {
+		sync.ValidationMode = ValidationModeOff
+	}
+
+	// Schema changes are not acted upon unless the user explicitly asks for it.
+	if sync.SchemaChangePolicy == "" {
+		sync.SchemaChangePolicy = SchemaChangePolicyIgnore
+	}
+	sync.SchemaChanges = nil
+
 	// Perform basic field validation.
 	if err := sync.Validate(); err != nil {
 		return err
 	}
 
+	// Both endpoints must belong to the workspace of the sync.
+	sync.WorkspaceID = workspaceOf(ctx)
+	for _, id := range []int{sync.SourceEndpointID, sync.DestinationEndpointID} {
+		endpoint, err := a.FindEndpointByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if endpoint.WorkspaceID != sync.WorkspaceID {
+			return Errorf(EINVALID, "The endpoints of a sync must belong to its workspace")
+		}
+	}
+
+	// Make sure that the filter and projection expressions compile.
+	if _, err := a.CompileStreamExpressions(ctx, sync); err != nil {
+		return err
+	}
+
 	sync.Enabled = false
 
 	config, err := json.Marshal(sync.Config.ToConfiguredCatalog())
@@ -139,7 +261,7 @@ func (a *App) CreateSync(ctx context.Context, sync *Sync) error {
 	}
 	sync.ConfiguredCatalog = *msg
 
-	return a.DBService.CreateSync(ctx, sync)
+	return a.DBService.CreateSync(NewAuditContext(ctx, AuditActionCreate, AuditEntitySync, nil, sync), sync)
 }
 
 func (a *App) UpdateSync(ctx context.Context, id int, upd *SyncUpdate) (*Sync, error) {
@@ -148,6 +270,7 @@ func (a *App) UpdateSync(ctx context.Context, id int, upd *SyncUpdate) (*Sync, e
 	if err != nil {
 		return nil, err
 	}
+	before := *sync
 
 	// Update fields if set.
 	if v := upd.Name; v != nil {
@@ -171,8 +294,21 @@ func (a *App) UpdateSync(ctx context.Context, id int, upd *SyncUpdate) (*Sync, e
 	if v := upd.StreamPrefix; v != nil {
 		sync.StreamPrefix = *v
 	}
+	if v := upd.ValidationMode; v != nil {
+		sync.ValidationMode = *v
+	}
+	if v := upd.SchemaChangePolicy; v != nil {
+		sync.SchemaChangePolicy = *v
+	}
 	if v := upd.Config; v != nil {
 		sync.Config = *v
+
+		// The new configuration is based on the most recently discovered catalog
+		// and therefore resolves all the pending schema changes.
+		sync.SchemaChanges = nil
+	}
+	if v := upd.SchemaChanges; v != nil {
+		sync.SchemaChanges = *v
 	}
 	if v := upd.State; v != nil {
 		sync.State = *v
@@ -186,6 +322,11 @@ func (a *App) UpdateSync(ctx context.Context, id int, upd *SyncUpdate) (*Sync, e
 		return nil, err
 	}
 
+	// Make sure that the filter and projection expressions compile.
+	if _, err := a.CompileStreamExpressions(ctx, sync); err != nil {
+		return nil, err
+	}
+
 	config, err := json.Marshal(sync.Config.ToConfiguredCatalog())
 	if err != nil {
 		return nil, err
@@ -196,9 +337,18 @@ func (a *App) UpdateSync(ctx context.Context, id int, upd *SyncUpdate) (*Sync, e
 	}
 	sync.ConfiguredCatalog = *msg
 
-	if err := a.DBService.UpdateSync(ctx, id, sync); err != nil {
+	if err := a.DBService.UpdateSync(NewAuditContext(ctx, AuditActionUpdate, AuditEntitySync, &before, sync), id, sync); err != nil {
 		return nil, err
 	}
 
 	return sync, nil
 }
+
+func (a *App) DeleteSync(ctx context.Context, id int) error {
+	sync, err := a.FindSyncByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return a.DBService.DeleteSync(NewAuditContext(ctx, AuditActionDelete, AuditEntitySync, sync, nil), id)
+}
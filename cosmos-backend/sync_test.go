@@ -0,0 +1,50 @@
+package cosmos
+
+import "testing"
+
+func TestSyncValidate(t *testing.T) {
+	// newSync returns a valid sync which the tests modify.
+	newSync := func() *Sync {
+		return &Sync{
+			Name:                  "users",
+			SourceEndpointID:      1,
+			DestinationEndpointID: 2,
+			NamespaceDefinition:   NamespaceDefinitionSource,
+			ValidationMode:        ValidationModeOff,
+			SchemaChangePolicy:    SchemaChangePolicyIgnore,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(s *Sync)
+		wantErr bool
+	}{
+		{"valid", func(s *Sync) {}, false},
+		{"validation mode warn", func(s *Sync) { s.ValidationMode = ValidationModeWarn }, false},
+		{"validation mode quarantine", func(s *Sync) { s.ValidationMode = ValidationModeQuarantine }, false},
+		{"validation mode fail", func(s *Sync) { s.ValidationMode = ValidationModeFail }, false},
+		{"empty validation mode", func(s *Sync) { s.ValidationMode = "" }, true},
+		{"invalid validation mode", func(s *Sync) { s.ValidationMode = "strict" }, true},
+		{"schema change policy propagate", func(s *Sync) { s.SchemaChangePolicy = SchemaChangePolicyPropagate }, false},
+		{"schema change policy pause", func(s *Sync) { s.SchemaChangePolicy = SchemaChangePolicyPause }, false},
+		{"empty schema change policy", func(s *Sync) { s.SchemaChangePolicy = "" }, true},
+		{"invalid schema change policy", func(s *Sync) { s.SchemaChangePolicy = "Propagate" }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sync := newSync()
+			tt.modify(sync)
+
+			err := sync.Validate()
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+			} else if code := ErrorCode(err); code != EINVALID {
+				t.Fatalf("unexpected error code: %q, want: %q", code, EINVALID)
+			}
+		})
+	}
+}
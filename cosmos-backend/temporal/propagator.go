@@ -0,0 +1,100 @@
+package temporal
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/workflow"
+)
+
+// traceHeader is the temporal header in which the trace context is propagated.
+// The SDK uses "_tracer-data" for its own opentracing spans.
+const traceHeader = "_cosmos-trace-context"
+
+type traceContextKey struct{}
+
+// traceCarrier holds the trace context in the temporal header.
+type traceCarrier map[string]string
+
+func (c traceCarrier) Get(key string) string {
+	return c[key]
+}
+
+func (c traceCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+func (c traceCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+var _ workflow.ContextPropagator = (*TracePropagator)(nil)
+
+// TracePropagator propagates the trace context from the worker which starts a workflow,
+// through the workflow, to its activities so that their spans belong to the same trace.
+type TracePropagator struct{}
+
+func NewTracePropagator() *TracePropagator {
+	return &TracePropagator{}
+}
+
+func (p *TracePropagator) Inject(ctx context.Context, hw workflow.HeaderWriter) error {
+	carrier := traceCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return writeTraceHeader(carrier, hw)
+}
+
+func (p *TracePropagator) Extract(ctx context.Context, hr workflow.HeaderReader) (context.Context, error) {
+	carrier, err := readTraceHeader(hr)
+	if err != nil || carrier == nil {
+		return ctx, err
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, carrier), nil
+}
+
+// InjectFromWorkflow passes on the trace context that the workflow was started with. Spans
+// are not started in the workflow itself because it is replayed from the beginning.
+func (p *TracePropagator) InjectFromWorkflow(ctx workflow.Context, hw workflow.HeaderWriter) error {
+	carrier, ok := ctx.Value(traceContextKey{}).(traceCarrier)
+	if !ok {
+		return nil
+	}
+	return writeTraceHeader(carrier, hw)
+}
+
+func (p *TracePropagator) ExtractToWorkflow(ctx workflow.Context, hr workflow.HeaderReader) (workflow.Context, error) {
+	carrier, err := readTraceHeader(hr)
+	if err != nil || carrier == nil {
+		return ctx, err
+	}
+	return workflow.WithValue(ctx, traceContextKey{}, carrier), nil
+}
+
+func writeTraceHeader(carrier traceCarrier, hw workflow.HeaderWriter) error {
+	if len(carrier) == 0 {
+		return nil
+	}
+	payload, err := converter.GetDefaultDataConverter().ToPayload(map[string]string(carrier))
+	if err != nil {
+		return err
+	}
+	hw.Set(traceHeader, payload)
+	return nil
+}
+
+func readTraceHeader(hr workflow.HeaderReader) (traceCarrier, error) {
+	payload, ok := hr.Get(traceHeader)
+	if !ok {
+		return nil, nil
+	}
+	carrier := traceCarrier{}
+	if err := converter.GetDefaultDataConverter().FromPayload(payload, &carrier); err != nil {
+		return nil, err
+	}
+	return carrier, nil
+}
@@ -3,12 +3,16 @@ package temporal
 import (
 	"context"
 	"cosmos"
+	"cosmos/opentelemetry"
 	"log"
 	"runtime/debug"
 	"strconv"
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.temporal.io/sdk/client"
 )
 
@@ -79,31 +83,47 @@ func (w *Worker) DoWork() {
 		log.Printf("worker err: %s", err)
 		return
 	}
+	w.App.ObserveQueueDepth(len(runs))
 
 	for _, run := range runs {
-		options := client.StartWorkflowOptions{ID: strconv.Itoa(run.SyncID), TaskQueue: cosmos.TemporalTaskQueue}
+		w.startRun(run)
+	}
+}
 
-		// If there is already a workflow running, ExecuteWorkflow() will simply return its run id without creating a new one.
-		wr, err := w.Client.ExecuteWorkflow(w.ctx, options, NewWorkflow().IngestionWorkflow, run.ID)
-		if err != nil {
-			log.Printf("worker failed to start temporal workflow. err: %s", err)
-			continue
-		}
-		temporalWorkflowID := wr.GetID()
-		temporalRunID := wr.GetRunID()
-
-		// Don't set the status to "running" here. It will be set in the workflow.
-		// Even if this UpdateRun fails, ExecuteWorkflow() will return the same run id next time around.
-		run, err = w.App.UpdateRun(
-			w.ctx,
-			run.ID,
-			&cosmos.RunUpdate{
-				TemporalWorkflowID: &temporalWorkflowID,
-				TemporalRunID:      &temporalRunID,
-			},
-		)
-		if err != nil {
-			log.Printf("worker err: %s", err)
-		}
+// startRun starts the workflow of a queued run. Its span continues the trace in which the run was
+// created (e.g, the trace of the request to sync now) and the trace context is propagated to the
+// activities of the workflow, so that the whole lifecycle of the run belongs to a single trace.
+func (w *Worker) startRun(run *cosmos.Run) {
+	ctx := opentelemetry.ContextWithTraceParent(w.ctx, run.TraceParent)
+	ctx, span := tracer.Start(ctx, "Worker.DoWork", trace.WithAttributes(
+		attribute.Int("run_id", run.ID),
+		attribute.Int("sync_id", run.SyncID),
+	))
+	defer span.End()
+
+	options := client.StartWorkflowOptions{ID: strconv.Itoa(run.SyncID), TaskQueue: cosmos.TemporalTaskQueue}
+
+	// If there is already a workflow running, ExecuteWorkflow() will simply return its run id without creating a new one.
+	wr, err := w.Client.ExecuteWorkflow(ctx, options, NewWorkflow().IngestionWorkflow, run.ID)
+	if err != nil {
+		log.Printf("worker failed to start temporal workflow. err: %s", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+	temporalWorkflowID := wr.GetID()
+	temporalRunID := wr.GetRunID()
+
+	// Don't set the status to "running" here. It will be set in the workflow.
+	// Even if this UpdateRun fails, ExecuteWorkflow() will return the same run id next time around.
+	if _, err := w.App.UpdateRun(
+		w.ctx,
+		run.ID,
+		&cosmos.RunUpdate{
+			TemporalWorkflowID: &temporalWorkflowID,
+			TemporalRunID:      &temporalRunID,
+		},
+	); err != nil {
+		log.Printf("worker err: %s", err)
 	}
 }
@@ -4,10 +4,15 @@ import (
 	"context"
 	"cosmos"
 	"errors"
+	"fmt"
 	"log"
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
@@ -17,6 +22,8 @@ import (
 
 var json = jsoniter.ConfigDefault
 
+var tracer = otel.Tracer("cosmos/temporal")
+
 type Workflow struct {
 	*cosmos.App
 }
@@ -72,12 +79,18 @@ func (w *Workflow) StartHeartbeat(ctx context.Context, period time.Duration, run
 
 				// Best effort stats updation. Errors are ignored.
 				numRecords := runCopy.Stats.NumRecords
+				numInvalidRecords := runCopy.Stats.NumInvalidRecords
+				numQuarantinedRecords := runCopy.Stats.NumQuarantinedRecords
+				numFilteredRecords := runCopy.Stats.NumFilteredRecords
 				executionStart := runCopy.Stats.ExecutionStart
 				executionEnd := time.Now()
 				w.App.UpdateRun(ctx, run.ID, &cosmos.RunUpdate{
-					NumRecords:     &numRecords,
-					ExecutionStart: &executionStart,
-					ExecutionEnd:   &executionEnd,
+					NumRecords:            &numRecords,
+					NumInvalidRecords:     &numInvalidRecords,
+					NumQuarantinedRecords: &numQuarantinedRecords,
+					NumFilteredRecords:    &numFilteredRecords,
+					ExecutionStart:        &executionStart,
+					ExecutionEnd:          &executionEnd,
 				})
 			} else {
 				activity.RecordHeartbeat(ctx)
@@ -128,7 +141,9 @@ func (w *Workflow) IngestionWorkflow(ctx workflow.Context, runID int) error {
 	return w.UpdateDB(ctx, run, err)
 }
 
-func (w *Workflow) GetRun(ctx context.Context, runID int) (*cosmos.Run, error) {
+func (w *Workflow) GetRun(ctx context.Context, runID int) (_ *cosmos.Run, err error) {
+	ctx, finish := w.startActivity(ctx, "get_run")
+	defer finish(&err)
 	defer close(w.StartHeartbeat(ctx, 5*time.Second, nil))
 
 	// Mark the workflow as running.
@@ -147,12 +162,17 @@ func (w *Workflow) GetRun(ctx context.Context, runID int) (*cosmos.Run, error) {
 	return run, nil
 }
 
-func (w *Workflow) Initialize(ctx context.Context, run *cosmos.Run) (*cosmos.Run, error) {
+func (w *Workflow) Initialize(ctx context.Context, run *cosmos.Run) (_ *cosmos.Run, err error) {
+	ctx, finish := w.startActivity(ctx, "initialize")
+	defer finish(&err)
 	defer close(w.StartHeartbeat(ctx, 5*time.Second, &RunWrapper{Run: run}))
 
 	state := run.Sync.State
-	srcConfig := run.Sync.SourceEndpoint.Config.ToSpec()
-	dstConfig := run.Sync.DestinationEndpoint.Config.ToSpec()
+
+	// The configs in the artifacts are only for display. The connectors are given
+	// the unredacted configs in ephemeral files that are removed once they exit.
+	srcConfig := w.App.RedactedConfig(ctx, run.Sync.SourceEndpoint)
+	dstConfig := w.App.RedactedConfig(ctx, run.Sync.DestinationEndpoint)
 	srcConfiguredCatalog := run.Sync.ConfiguredCatalog.ConfiguredCatalog
 
 	// In order to wipe the destination clean, we do a "full_refresh - overwrite" sync
@@ -167,12 +187,37 @@ func (w *Workflow) Initialize(ctx context.Context, run *cosmos.Run) (*cosmos.Run
 		}
 	}
 
-	// Modify the streams in the destination configuredCatalog according to the
-	// namespace definition and stream prefix provided by the user.
+	// The destination must be told about fields which are dropped or whose
+	// type changes because of the field policies and the projections.
+	fieldPolicies := run.Sync.Config.FieldPolicies()
+	expressions, err := w.App.CompileStreamExpressions(ctx, run.Sync)
+	if err != nil {
+		return nil, err
+	}
+
+	// Modify the streams in the destination configuredCatalog according to the field
+	// policies, projections, namespace definition and stream prefix provided by the user.
 	dstConfiguredCatalog := &cosmos.ConfiguredCatalog{}
 	DeepCopy(srcConfiguredCatalog, dstConfiguredCatalog)
 	for i := range dstConfiguredCatalog.Streams {
-		run.Sync.NamespaceMapper(&dstConfiguredCatalog.Streams[i].Stream)
+		stream := &dstConfiguredCatalog.Streams[i].Stream
+		for _, policy := range fieldPolicies[stream.Key()] {
+			policy.ApplyToSchema(&stream.JSONSchema)
+		}
+		if e, ok := expressions[stream.Key()]; ok {
+			if stream.JSONSchema, err = e.ProjectSchema(stream.JSONSchema); err != nil {
+				return nil, err
+			}
+		}
+		run.Sync.NamespaceMapper(stream)
+	}
+
+	// Salts must not be visible in the artifacts.
+	maskedFieldPolicies := map[string][]*cosmos.FieldPolicy{}
+	for stream, policies := range fieldPolicies {
+		for _, policy := range policies {
+			maskedFieldPolicies[stream] = append(maskedFieldPolicies[stream], policy.Masked())
+		}
 	}
 
 	artifactory, err := w.App.GetArtifactory(run.SyncID, run.ExecutionDate)
@@ -197,11 +242,17 @@ func (w *Workflow) Initialize(ctx context.Context, run *cosmos.Run) (*cosmos.Run
 	if err := w.App.WriteArtifact(artifactory, cosmos.ArtifactDstCatalog, dstConfiguredCatalog); err != nil {
 		return nil, err
 	}
+	if err := w.App.WriteArtifact(artifactory, cosmos.ArtifactFieldPolicies, maskedFieldPolicies); err != nil {
+		return nil, err
+	}
 
 	return run, nil
 }
 
-func (w *Workflow) ReplicationActivity(ctx context.Context, run *cosmos.Run) (*cosmos.Run, error) {
+func (w *Workflow) ReplicationActivity(ctx context.Context, run *cosmos.Run) (_ *cosmos.Run, err error) {
+	ctx, finish := w.startActivity(ctx, "replication")
+	defer finish(&err)
+
 	// Get heartbeat details from a previous attempt (if any).
 	if activity.HasHeartbeatDetails(ctx) {
 		if err := activity.GetHeartbeatDetails(ctx, run); err != nil {
@@ -232,6 +283,9 @@ func (w *Workflow) ReplicationActivity(ctx context.Context, run *cosmos.Run) (*c
 		return nil, err
 	}
 
+	logRetry(workerArtifact, "replication", attempt)
+	workerArtifact.Println(&cosmos.Event{Type: cosmos.EventReplicationStart, Message: "Replication started"})
+
 	srcConnector := run.Sync.SourceEndpoint.Connector
 	dstConnector := run.Sync.DestinationEndpoint.Connector
 
@@ -239,9 +293,18 @@ func (w *Workflow) ReplicationActivity(ctx context.Context, run *cosmos.Run) (*c
 	runctx = cosmos.NewArtifactoryContext(runctx, artifactory)
 	defer cancel()
 
-	s1out, s1errc := w.App.Read(runctx, srcConnector, run.Options.WipeDestination)
+	srcConfig, err := w.App.ConnectorConfig(ctx, &run.Sync.SourceEndpoint.Config)
+	if err != nil {
+		return nil, err
+	}
+	dstConfig, err := w.App.ConnectorConfig(ctx, &run.Sync.DestinationEndpoint.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	s1out, s1errc := w.App.Read(runctx, srcConnector, srcConfig, run.Options.WipeDestination)
 	s2out, s2errc := w.ProcessSourceConnectorOutput(runctx, s1out, runWrapper, run.Sync, attempt)
-	s3out, s3errc := w.App.Write(runctx, dstConnector, s2out)
+	s3out, s3errc := w.App.Write(runctx, dstConnector, dstConfig, s2out)
 	s4errc := w.ProcessDestinationConnectorOutput(runctx, s3out, runWrapper, attempt)
 
 	cancel()
@@ -254,6 +317,10 @@ func (w *Workflow) ReplicationActivity(ctx context.Context, run *cosmos.Run) (*c
 		}
 	}
 
+	runWrapper.Lock()
+	workerArtifact.Println(&cosmos.Event{Type: cosmos.EventLastState, Message: stateString(run.Sync.State)})
+	runWrapper.Unlock()
+
 	// If you want the workflow to get partial results even on error, you should send them via ApplicationError.
 	if finalErr != nil {
 		return nil, temporal.NewApplicationErrorWithCause("replication activity failed", "", finalErr, run)
@@ -265,7 +332,9 @@ func (w *Workflow) ReplicationActivity(ctx context.Context, run *cosmos.Run) (*c
 	return run, nil
 }
 
-func (w *Workflow) NormalizationActivity(ctx context.Context, run *cosmos.Run) (*cosmos.Run, error) {
+func (w *Workflow) NormalizationActivity(ctx context.Context, run *cosmos.Run) (_ *cosmos.Run, err error) {
+	ctx, finish := w.startActivity(ctx, "normalization")
+	defer finish(&err)
 	defer close(w.StartHeartbeat(ctx, 5*time.Second, &RunWrapper{Run: run}))
 
 	// Current attempt number.
@@ -283,6 +352,9 @@ func (w *Workflow) NormalizationActivity(ctx context.Context, run *cosmos.Run) (
 		return nil, err
 	}
 
+	logRetry(workerArtifact, "normalization", attempt)
+	workerArtifact.Println(&cosmos.Event{Type: cosmos.EventNormalizationStart, Message: "Normalization started"})
+
 	dstConnector := run.Sync.DestinationEndpoint.Connector
 	basicNormalization := run.Sync.BasicNormalization
 
@@ -290,7 +362,12 @@ func (w *Workflow) NormalizationActivity(ctx context.Context, run *cosmos.Run) (
 	runctx = cosmos.NewArtifactoryContext(runctx, artifactory)
 	defer cancel()
 
-	s1out, s1errc := w.App.Normalize(runctx, dstConnector, basicNormalization)
+	dstConfig, err := w.App.ConnectorConfig(ctx, &run.Sync.DestinationEndpoint.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	s1out, s1errc := w.App.Normalize(runctx, dstConnector, dstConfig, basicNormalization)
 	s2errc := w.ProcessNormalizationOutput(runctx, s1out, attempt)
 
 	cancel()
@@ -338,7 +415,9 @@ func (w *Workflow) UpdateDB(ctx workflow.Context, run *cosmos.Run, err error) er
 	return workflow.ExecuteActivity(ctx, w.DBUpdateActivity, run).Get(ctx, nil)
 }
 
-func (w *Workflow) DBUpdateActivity(ctx context.Context, run *cosmos.Run) error {
+func (w *Workflow) DBUpdateActivity(ctx context.Context, run *cosmos.Run) (err error) {
+	ctx, finish := w.startActivity(ctx, "db_update")
+	defer finish(&err)
 	defer close(w.StartHeartbeat(ctx, 5*time.Second, &RunWrapper{Run: run}))
 
 	// State must be updated in the sync before setting the run status to a terminal state.
@@ -361,13 +440,21 @@ func (w *Workflow) DBUpdateActivity(ctx context.Context, run *cosmos.Run) error
 
 	// Update the run in the DB.
 	_, err = w.App.UpdateRun(ctx, run.ID, &cosmos.RunUpdate{
-		Status:         &run.Status,
-		NumRecords:     &run.Stats.NumRecords,
-		ExecutionStart: &run.Stats.ExecutionStart,
-		ExecutionEnd:   &run.Stats.ExecutionEnd,
+		Status:                &run.Status,
+		NumRecords:            &run.Stats.NumRecords,
+		NumInvalidRecords:     &run.Stats.NumInvalidRecords,
+		NumQuarantinedRecords: &run.Stats.NumQuarantinedRecords,
+		NumFilteredRecords:    &run.Stats.NumFilteredRecords,
+		ExecutionStart:        &run.Stats.ExecutionStart,
+		ExecutionEnd:          &run.Stats.ExecutionEnd,
 	})
+	if err != nil {
+		return err
+	}
+
+	w.App.ObserveRun(run.SyncID, run.Status, run.Stats.ExecutionEnd.Sub(run.Stats.ExecutionStart))
 
-	return err
+	return nil
 }
 
 func (w *Workflow) ProcessSourceConnectorOutput(ctx context.Context, in <-chan interface{}, run *RunWrapper, sync *cosmos.Sync, attempt int32) (<-chan *cosmos.Message, <-chan error) {
@@ -384,9 +471,122 @@ func (w *Workflow) ProcessSourceConnectorOutput(ctx context.Context, in <-chan i
 			return
 		}
 
+		validators := w.getRecordValidators(ctx, sync, sourceArtifact)
+		excludedFields := map[string][]string{}
+		for _, field := range sync.Config.Catalog {
+			excludedFields[field.Stream.Key()] = field.ExcludedFields()
+		}
+		fieldPolicies := sync.Config.FieldPolicies()
+		expressions, err := w.App.CompileStreamExpressions(ctx, sync)
+		if err != nil {
+			errc <- err
+			return
+		}
+
+		// Keep a small sample of the records sent to the destination from each stream.
+		samples := map[string][]map[string]interface{}{}
+		defer func() {
+			if err := w.App.WriteArtifact(artifactory, cosmos.ArtifactSample, samples); err != nil {
+				sourceArtifact.Println(&cosmos.Log{Level: cosmos.LogLevelWarn, Message: fmt.Sprintf("Unable to write the record sample. %s", err)})
+			}
+		}()
+
+		var quarantineArtifact *cosmos.ArtifactLogger
+		if sync.ValidationMode == cosmos.ValidationModeQuarantine {
+			quarantineArtifact, err = w.App.GetArtifactRef(artifactory, cosmos.ArtifactQuarantine, attempt)
+			if err != nil {
+				errc <- err
+				return
+			}
+		}
+
+		firstRecord := true
 		for line := range in {
 			if msg, ok := line.(*cosmos.Message); ok {
 				if msg.Type == cosmos.MessageTypeRecord {
+					if firstRecord {
+						firstRecord = false
+						sourceArtifact.Println(&cosmos.Event{
+							Type:    cosmos.EventFirstRecord,
+							Message: fmt.Sprintf("First record received from stream %s", msg.Record.StreamKey()),
+						})
+					}
+
+					// Strip the excluded fields since not all sources support column selection.
+					for _, field := range excludedFields[msg.Record.StreamKey()] {
+						delete(msg.Record.Data, field)
+					}
+
+					// Validate the record against the JSON schema of its stream.
+					if validator, ok := validators[msg.Record.StreamKey()]; ok {
+						if err := validator.ValidateRecord(msg.Record); err != nil {
+							run.Lock()
+							run.Stats.NumInvalidRecords++
+							run.Unlock()
+
+							switch sync.ValidationMode {
+							case cosmos.ValidationModeWarn:
+								sourceArtifact.Println(&cosmos.Log{
+									Level:   cosmos.LogLevelWarn,
+									Message: fmt.Sprintf("Invalid record in stream %s: %s", msg.Record.StreamKey(), cosmos.ErrorMessage(err)),
+								})
+							case cosmos.ValidationModeQuarantine:
+								// Quarantined records must not leak the fields masked by the field policies.
+								for _, policy := range fieldPolicies[msg.Record.StreamKey()] {
+									policy.Apply(msg.Record.Data)
+								}
+								b, err := json.Marshal(&QuarantinedRecord{Error: cosmos.ErrorMessage(err), Record: msg.Record})
+								if err != nil {
+									errc <- err
+									return
+								}
+								quarantineArtifact.Println(string(b))
+								run.Lock()
+								run.Stats.NumQuarantinedRecords++
+								run.Unlock()
+								continue
+							case cosmos.ValidationModeFail:
+								errc <- fmt.Errorf("invalid record in stream %s: %s", msg.Record.StreamKey(), cosmos.ErrorMessage(err))
+								return
+							}
+						}
+					}
+
+					// Drop the records which don't satisfy the filter. The filter only decides whether a
+					// record is kept, so it is evaluated against the unmasked record.
+					e, hasExpressions := expressions[msg.Record.StreamKey()]
+					if hasExpressions {
+						keep, err := e.Keep(msg.Record.Data)
+						if err != nil {
+							errc <- fmt.Errorf("failed to evaluate filter on stream %s: %w", msg.Record.StreamKey(), err)
+							return
+						}
+						if !keep {
+							run.Lock()
+							run.Stats.NumFilteredRecords++
+							run.Unlock()
+							continue
+						}
+					}
+
+					// Mask sensitive fields before the record leaves cosmos. This must happen before the
+					// projection, which may rename the fields that the policies refer to.
+					for _, policy := range fieldPolicies[msg.Record.StreamKey()] {
+						policy.Apply(msg.Record.Data)
+					}
+
+					if hasExpressions {
+						var err error
+						if msg.Record.Data, err = e.Project(msg.Record.Data); err != nil {
+							errc <- fmt.Errorf("failed to evaluate projection on stream %s: %w", msg.Record.StreamKey(), err)
+							return
+						}
+					}
+
+					if key := msg.Record.StreamKey(); len(samples[key]) < cosmos.SampleRecordsPerStream {
+						samples[key] = append(samples[key], msg.Record.Data)
+					}
+
 					// Modify the record according to the namespace definition and stream prefix provided by the user.
 					sync.NamespaceMapper(msg.Record)
 					if err := sendMsgOnChannel(ctx, msg, out); err != nil {
@@ -395,7 +595,9 @@ func (w *Workflow) ProcessSourceConnectorOutput(ctx context.Context, in <-chan i
 					run.Lock()
 					run.Stats.NumRecords++
 					run.Unlock()
+					w.App.ObserveRecords(sync.ID, msg.Record.StreamKey(), 1)
 				} else if msg.Type == cosmos.MessageTypeState {
+					sourceArtifact.Println(&cosmos.Event{Type: cosmos.EventState, Message: stateString(msg.State.Data)})
 					if err := sendMsgOnChannel(ctx, msg, out); err != nil {
 						break
 					}
@@ -418,6 +620,36 @@ func (w *Workflow) ProcessSourceConnectorOutput(ctx context.Context, in <-chan i
 	return out, errc
 }
 
+// QuarantinedRecord represents a record that failed validation along with the validation error.
+type QuarantinedRecord struct {
+	Error  string         `json:"error"`
+	Record *cosmos.Record `json:"record"`
+}
+
+// getRecordValidators returns a record validator for each stream in the configured catalog of the sync.
+// Streams whose JSON schema cannot be compiled are not validated.
+func (w *Workflow) getRecordValidators(ctx context.Context, sync *cosmos.Sync, sourceArtifact *cosmos.ArtifactLogger) map[string]cosmos.RecordValidator {
+	validators := map[string]cosmos.RecordValidator{}
+
+	if sync.ValidationMode == cosmos.ValidationModeOff || sync.ConfiguredCatalog.ConfiguredCatalog == nil {
+		return validators
+	}
+
+	for _, s := range sync.ConfiguredCatalog.ConfiguredCatalog.Streams {
+		validator, err := w.App.NewRecordValidator(ctx, &s.Stream)
+		if err != nil {
+			sourceArtifact.Println(&cosmos.Log{
+				Level:   cosmos.LogLevelWarn,
+				Message: fmt.Sprintf("Records in stream %s will not be validated. %s", s.Stream.Key(), err),
+			})
+			continue
+		}
+		validators[s.Stream.Key()] = validator
+	}
+
+	return validators
+}
+
 func (w *Workflow) ProcessDestinationConnectorOutput(ctx context.Context, in <-chan interface{}, run *RunWrapper, attempt int32) <-chan error {
 	errc := make(chan error, 1)
 
@@ -434,6 +666,7 @@ func (w *Workflow) ProcessDestinationConnectorOutput(ctx context.Context, in <-c
 				run.Lock()
 				run.Sync.State = msg.State.Data
 				run.Unlock()
+				destinationArtifact.Println(&cosmos.Event{Type: cosmos.EventState, Message: stateString(msg.State.Data)})
 			} else if msg.Type == cosmos.MessageTypeLog {
 				destinationArtifact.Println(msg.Log)
 			} else {
@@ -480,6 +713,45 @@ func (w *Workflow) ProcessNormalizationOutput(ctx context.Context, in <-chan int
 	return errc
 }
 
+// startActivity starts the span of an activity as a child of the span which started the workflow (if any).
+// The returned function must be deferred with the error of the activity. It ends the span and records the
+// duration of the activity and the error (if any) that it failed with.
+func (w *Workflow) startActivity(ctx context.Context, name string) (context.Context, func(*error)) {
+	start := time.Now()
+	ctx, span := tracer.Start(ctx, "activity "+name, trace.WithAttributes(
+		attribute.String("activity", name),
+		attribute.Int("attempt", int(activity.GetInfo(ctx).Attempt)),
+	))
+
+	return ctx, func(err *error) {
+		w.App.ObserveActivity(name, time.Since(start), *err)
+		if *err != nil {
+			span.RecordError(*err)
+			span.SetStatus(codes.Error, (*err).Error())
+		}
+		span.End()
+	}
+}
+
+// logRetry writes a retry event to the worker artifact if the activity is being retried.
+func logRetry(workerArtifact *cosmos.ArtifactLogger, activity string, attempt int32) {
+	if attempt > 1 {
+		workerArtifact.Println(&cosmos.Event{
+			Type:    cosmos.EventRetry,
+			Message: fmt.Sprintf("Retrying %s. Attempt %d", activity, attempt),
+		})
+	}
+}
+
+// stateString renders a state checkpoint for the timeline of the run.
+func stateString(state interface{}) string {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Sprintf("%v", state)
+	}
+	return string(b)
+}
+
 func sendMsgOnChannel(ctx context.Context, msg *cosmos.Message, ch chan<- *cosmos.Message) error {
 	select {
 	case ch <- msg:
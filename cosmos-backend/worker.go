@@ -5,3 +5,11 @@ import "context"
 type WorkerService interface {
 	CancelRun(ctx context.Context, runID int) error
 }
+
+func (a *App) CancelRun(ctx context.Context, runID int) error {
+	if err := a.WorkerService.CancelRun(ctx, runID); err != nil {
+		return err
+	}
+
+	return a.audit(ctx, AuditActionCancel, AuditEntityRun, runID, nil, nil)
+}
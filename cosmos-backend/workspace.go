@@ -0,0 +1,228 @@
+package cosmos
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultWorkspaceID is the workspace that everything created before workspaces were introduced belongs to.
+// It is also the workspace of API requests which don't name one and cannot be deleted.
+const DefaultWorkspaceID = 1
+
+const workspaceKey ctxKey = "workspace"
+
+// Workspace isolates the endpoints, syncs and private connectors of a team from those of other teams.
+// Names of endpoints and syncs only need to be unique within a workspace.
+type Workspace struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (w *Workspace) Validate() error {
+	if w.Name == "" {
+		return Errorf(EINVALID, "Workspace name required")
+	}
+	return nil
+}
+
+// WorkspaceUpdate represents workspace fields that can be updated.
+type WorkspaceUpdate struct {
+	Name *string `json:"name"`
+}
+
+// WorkspaceFilter represents a workspace search filter.
+type WorkspaceFilter struct {
+	ID   *int    `json:"id"`
+	Name *string `json:"name"`
+
+	// UserID restricts the workspaces to the ones that the user is a member of.
+	UserID *int `json:"-"`
+
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// WorkspaceMember gives a user access to a workspace with a role that applies to everything in it.
+// All users are members of the default workspace with the role of the user, and admins have
+// access to all workspaces, so they are not listed as members.
+type WorkspaceMember struct {
+	ID          int       `json:"id"`
+	WorkspaceID int       `json:"workspaceID"`
+	UserID      int       `json:"userID"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// Validate performs some basic validation on the member object during create.
+func (m *WorkspaceMember) Validate() error {
+	if !IsValidRole(m.Role) {
+		return Errorf(EINVALID, "Role must be one of 'none', 'viewer', 'operator' or 'admin'")
+	} else if m.WorkspaceID == DefaultWorkspaceID {
+		return Errorf(EINVALID, "All users are members of the default workspace")
+	}
+	return nil
+}
+
+// WorkspaceMemberFilter represents a workspace member search filter.
+type WorkspaceMemberFilter struct {
+	ID          *int `json:"id"`
+	WorkspaceID *int `json:"workspaceID"`
+	UserID      *int `json:"userID"`
+
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+type WorkspaceService interface {
+	FindWorkspaceByID(ctx context.Context, id int) (*Workspace, error)
+	FindWorkspaces(ctx context.Context, filter WorkspaceFilter) ([]*Workspace, int, error)
+	CreateWorkspace(ctx context.Context, workspace *Workspace) error
+	UpdateWorkspace(ctx context.Context, id int, workspace *Workspace) error
+	DeleteWorkspace(ctx context.Context, id int) error
+
+	FindWorkspaceMemberByID(ctx context.Context, id int) (*WorkspaceMember, error)
+	FindWorkspaceMembers(ctx context.Context, filter WorkspaceMemberFilter) ([]*WorkspaceMember, int, error)
+	CreateWorkspaceMember(ctx context.Context, member *WorkspaceMember) error
+	DeleteWorkspaceMember(ctx context.Context, id int) error
+}
+
+// NewWorkspaceContext returns a context which restricts connectors, endpoints, syncs and runs to the given workspace.
+func NewWorkspaceContext(ctx context.Context, workspaceID int) context.Context {
+	return context.WithValue(ctx, workspaceKey, workspaceID)
+}
+
+// WorkspaceFromContext returns the workspace that ctx is restricted to. ok is false if ctx is
+// not restricted to a workspace, which is the case for everything that cosmos does by itself.
+func WorkspaceFromContext(ctx context.Context) (workspaceID int, ok bool) {
+	workspaceID, ok = ctx.Value(workspaceKey).(int)
+	return workspaceID, ok
+}
+
+// workspaceOf returns the workspace that new entities are created in.
+func workspaceOf(ctx context.Context) int {
+	if workspaceID, ok := WorkspaceFromContext(ctx); ok {
+		return workspaceID
+	}
+	return DefaultWorkspaceID
+}
+
+func (a *App) CreateWorkspace(ctx context.Context, workspace *Workspace) error {
+	if err := workspace.Validate(); err != nil {
+		return err
+	}
+
+	return a.DBService.CreateWorkspace(NewAuditContext(ctx, AuditActionCreate, AuditEntityWorkspace, nil, workspace), workspace)
+}
+
+func (a *App) UpdateWorkspace(ctx context.Context, id int, upd *WorkspaceUpdate) (*Workspace, error) {
+	workspace, err := a.FindWorkspaceByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	before := *workspace
+
+	if v := upd.Name; v != nil {
+		workspace.Name = *v
+	}
+
+	if err := workspace.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := a.DBService.UpdateWorkspace(NewAuditContext(ctx, AuditActionUpdate, AuditEntityWorkspace, &before, workspace), id, workspace); err != nil {
+		return nil, err
+	}
+
+	return workspace, nil
+}
+
+// DeleteWorkspace deletes an empty workspace. Its endpoints, syncs and private connectors must be deleted first.
+func (a *App) DeleteWorkspace(ctx context.Context, id int) error {
+	if id == DefaultWorkspaceID {
+		return Errorf(EINVALID, "The default workspace cannot be deleted")
+	}
+
+	workspace, err := a.FindWorkspaceByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return a.DBService.DeleteWorkspace(NewAuditContext(ctx, AuditActionDelete, AuditEntityWorkspace, workspace, nil), id)
+}
+
+func (a *App) CreateWorkspaceMember(ctx context.Context, member *WorkspaceMember) error {
+	// Perform basic field validation.
+	if err := member.Validate(); err != nil {
+		return err
+	}
+
+	// Make sure that the workspace and the user exist.
+	if _, err := a.FindWorkspaceByID(ctx, member.WorkspaceID); err != nil {
+		return err
+	}
+	if _, err := a.FindUserByID(ctx, member.UserID); err != nil {
+		return err
+	}
+
+	return a.DBService.CreateWorkspaceMember(NewAuditContext(ctx, AuditActionCreate, AuditEntityWorkspaceMember, nil, member), member)
+}
+
+func (a *App) DeleteWorkspaceMember(ctx context.Context, id int) error {
+	member, err := a.FindWorkspaceMemberByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return a.DBService.DeleteWorkspaceMember(NewAuditContext(ctx, AuditActionDelete, AuditEntityWorkspaceMember, member, nil), id)
+}
+
+// WorkspaceRole returns the role of the user in the workspace. ok is false if the user is not a member of it.
+func (a *App) WorkspaceRole(ctx context.Context, user *User, workspaceID int) (role string, ok bool, err error) {
+	if workspaceID == DefaultWorkspaceID || HasRole(user.Role, RoleAdmin) {
+		return user.Role, true, nil
+	}
+
+	members, _, err := a.FindWorkspaceMembers(ctx, WorkspaceMemberFilter{WorkspaceID: &workspaceID, UserID: &user.ID})
+	if err != nil {
+		return "", false, err
+	} else if len(members) == 0 {
+		return "", false, nil
+	}
+	return members[0].Role, true, nil
+}
+
+// AuthorizeWorkspace returns an EFORBIDDEN error unless the authenticated user is a member of the workspace that ctx is restricted to.
+func (a *App) AuthorizeWorkspace(ctx context.Context) error {
+	user := UserFromContext(ctx)
+	if user == nil {
+		return Errorf(EUNAUTHORIZED, "Authentication required")
+	}
+
+	workspaceID, ok := WorkspaceFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	if _, ok, err := a.WorkspaceRole(ctx, user, workspaceID); err != nil {
+		return err
+	} else if !ok {
+		return Errorf(EFORBIDDEN, "You are not a member of this workspace")
+	}
+	return nil
+}
+
+// roleOf returns the role of the user in the workspace that ctx is restricted to, or the role of the user if it isn't.
+func (a *App) roleOf(ctx context.Context, user *User) (string, error) {
+	workspaceID, ok := WorkspaceFromContext(ctx)
+	if !ok {
+		return user.Role, nil
+	}
+	role, ok, err := a.WorkspaceRole(ctx, user, workspaceID)
+	if err != nil {
+		return "", err
+	} else if !ok {
+		return RoleNone, nil
+	}
+	return role, nil
+}
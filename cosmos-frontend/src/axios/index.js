@@ -1,4 +1,5 @@
 import axios from 'axios'
+import router from '../router'
 
 // Create an axios instance with a custom baseURL.
 // This is useful during development when the backend API server is running on a different port.
@@ -11,4 +12,24 @@ import axios from 'axios'
 const VueAxios = axios.create()
 VueAxios.defaults.baseURL = process.env.VUE_APP_API_ROOT
 
+// Send the session cookie along with cross-origin requests during development.
+VueAxios.defaults.withCredentials = true
+
+// Scope every request to the workspace selected in the navbar.
+VueAxios.interceptors.request.use(function(config) {
+  const workspaceID = localStorage.getItem('workspaceID')
+  if (workspaceID) {
+    config.headers['X-Workspace-ID'] = workspaceID
+  }
+  return config
+})
+
+// Redirect to the login page when the session has expired or the user isn't logged in.
+VueAxios.interceptors.response.use(undefined, function(error) {
+  if (error.response && error.response.status === 401 && router.currentRoute.name !== 'Login') {
+    router.push({name: 'Login', query: {redirect: router.currentRoute.fullPath}})
+  }
+  return Promise.reject(error)
+})
+
 export default VueAxios